@@ -0,0 +1,15 @@
+package activities
+
+import (
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/worker"
+)
+
+// Names are the well-known names the benchmark activities are registered with.
+var Names = []string{"Sleep", "Echo"}
+
+// Register registers the benchmark activities with their well-known names.
+func Register(r worker.ActivityRegistry) {
+	r.RegisterActivityWithOptions(SleepActivity, activity.RegisterOptions{Name: "Sleep"})
+	r.RegisterActivityWithOptions(EchoActivity, activity.RegisterOptions{Name: "Echo"})
+}
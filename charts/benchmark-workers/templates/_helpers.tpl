@@ -50,4 +50,14 @@ Selector labels
 app.kubernetes.io/name: {{ include "benchmark-workers.name" . }}
 app.kubernetes.io/instance: {{ .Release.Name }}
 app: benchmark
-{{- end }} 
\ No newline at end of file
+{{- end }} 
+{{/*
+Metric labels, as comma separated name=value pairs for PROMETHEUS_LABELS
+*/}}
+{{- define "benchmark-workers.metricsLabels" -}}
+{{- $labels := list }}
+{{- range $name, $value := . }}
+{{- $labels = append $labels (printf "%s=%v" $name $value) }}
+{{- end }}
+{{- join "," $labels }}
+{{- end }}
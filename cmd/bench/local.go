@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"time"
+
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/testsuite"
+)
+
+func runLocal(args []string) error {
+	fs := flag.NewFlagSet("local", flag.ExitOnError)
+	sScenario := fs.String("scenario", "echo", "scenario to run")
+	dDuration := fs.Duration("d", time.Minute, "how long to run the scenario for")
+	nWorkflows := fs.Int("c", 10, "concurrent workflows")
+	sResultsFile := fs.String("o", "results.json", "file to write the run results to")
+	sNamespace := fs.String("n", "default", "namespace")
+	sTaskQueue := fs.String("tq", "benchmark", "task queue")
+	sCLIPath := fs.String("temporal-cli", os.Getenv("TEMPORAL_CLI_PATH"), "path to an existing temporal CLI binary (downloaded and cached if unset)")
+	sCLIVersion := fs.String("temporal-cli-version", "default", "temporal CLI version to download if no binary is given")
+	sCacheDir := fs.String("cache-dir", "", "directory to cache the downloaded temporal CLI in (default: system temp dir)")
+	sWorkerBin := fs.String("worker-bin", "", "path to the worker binary (default: next to this binary, then PATH)")
+	sRunnerBin := fs.String("runner-bin", "", "path to the runner binary (default: next to this binary, then PATH)")
+	bList := fs.Bool("list", false, "list available scenarios and exit")
+
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s local [flags]\n", os.Args[0])
+		fs.PrintDefaults()
+		fmt.Fprintf(fs.Output(), "\nScenarios:\n")
+		printScenarios()
+	}
+	fs.Parse(args)
+
+	if *bList {
+		printScenarios()
+		return nil
+	}
+
+	sc, ok := scenarios[*sScenario]
+	if !ok {
+		return fmt.Errorf("unknown scenario: %s", *sScenario)
+	}
+
+	workerBin, err := findBinary("worker", *sWorkerBin)
+	if err != nil {
+		return fmt.Errorf("unable to find worker binary: %w", err)
+	}
+	runnerBin, err := findBinary("runner", *sRunnerBin)
+	if err != nil {
+		return fmt.Errorf("unable to find runner binary: %w", err)
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	log.Printf("Starting Temporal dev server")
+	server, err := testsuite.StartDevServer(ctx, testsuite.DevServerOptions{
+		ExistingPath: *sCLIPath,
+		CachedDownload: testsuite.CachedDownload{
+			Version: *sCLIVersion,
+			DestDir: *sCacheDir,
+		},
+		ClientOptions: &client.Options{Namespace: *sNamespace},
+		LogLevel:      "error",
+	})
+	if err != nil {
+		return fmt.Errorf("unable to start dev server: %w", err)
+	}
+	server.Client().Close()
+	defer server.Stop()
+
+	log.Printf("Dev server listening on %s", server.FrontendHostPort())
+
+	env := append(os.Environ(),
+		"TEMPORAL_GRPC_ENDPOINT="+server.FrontendHostPort(),
+		"TEMPORAL_NAMESPACE="+*sNamespace,
+		"TEMPORAL_TASK_QUEUE="+*sTaskQueue,
+	)
+
+	workerCmd := exec.Command(workerBin)
+	workerCmd.Env = env
+	workerCmd.Stdout = os.Stdout
+	workerCmd.Stderr = os.Stderr
+	if err := workerCmd.Start(); err != nil {
+		return fmt.Errorf("unable to start worker: %w", err)
+	}
+	defer func() {
+		workerCmd.Process.Signal(os.Interrupt)
+		workerCmd.Wait()
+	}()
+
+	runnerArgs := []string{
+		"-c", strconv.Itoa(*nWorkflows),
+		"-d", dDuration.String(),
+		"-o", *sResultsFile,
+		"-t", sc.WorkflowType,
+		"-scenario", *sScenario,
+	}
+	if sc.SignalType != "" {
+		runnerArgs = append(runnerArgs, "-s", sc.SignalType)
+	}
+	runnerArgs = append(runnerArgs, sc.Input...)
+
+	log.Printf("Running scenario %s for %s", *sScenario, *dDuration)
+
+	runnerCmd := exec.CommandContext(ctx, runnerBin, runnerArgs...)
+	runnerCmd.Env = env
+	runnerCmd.Stdout = os.Stdout
+	runnerCmd.Stderr = os.Stderr
+	runnerCmd.Cancel = func() error {
+		return runnerCmd.Process.Signal(os.Interrupt)
+	}
+	if err := runnerCmd.Run(); err != nil {
+		return fmt.Errorf("runner failed: %w", err)
+	}
+
+	log.Printf("Results written to %s", *sResultsFile)
+	return nil
+}
+
+// findBinary locates one of the benchmark binaries, preferring an explicit
+// path, then a binary alongside this one, then PATH.
+func findBinary(name, override string) (string, error) {
+	if override != "" {
+		return override, nil
+	}
+	if exe, err := os.Executable(); err == nil {
+		candidate := filepath.Join(filepath.Dir(exe), name)
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate, nil
+		}
+	}
+	return exec.LookPath(name)
+}
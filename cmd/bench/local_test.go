@@ -0,0 +1,75 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/temporalio/benchmark-workers/internal/devserver"
+	"github.com/temporalio/benchmark-workers/workflows"
+)
+
+func TestScenarios(t *testing.T) {
+	for name, sc := range scenarios {
+		require.Contains(t, workflows.Names, sc.WorkflowType, name)
+		require.NotEmpty(t, sc.Input, name)
+		for _, input := range sc.Input {
+			require.True(t, json.Valid([]byte(input)), "%s: %s", name, input)
+		}
+	}
+}
+
+func TestFindBinary(t *testing.T) {
+	path, err := findBinary("worker", "/opt/worker")
+	require.NoError(t, err)
+	require.Equal(t, "/opt/worker", path)
+
+	dir := t.TempDir()
+	bin := filepath.Join(dir, "benchmark-test-binary")
+	require.NoError(t, os.WriteFile(bin, []byte("#!/bin/sh\n"), 0o755))
+	t.Setenv("PATH", dir)
+	path, err = findBinary("benchmark-test-binary", "")
+	require.NoError(t, err)
+	require.Equal(t, bin, path)
+
+	_, err = findBinary("benchmark-missing-binary", "")
+	require.Error(t, err)
+}
+
+func TestRunLocal(t *testing.T) {
+	// runLocal starts its own dev server; starting one here first skips the
+	// test where that isn't possible, and caches the download for runLocal.
+	devserver.Start(t)
+
+	dir := t.TempDir()
+	for _, name := range []string{"worker", "runner"} {
+		out, err := exec.Command("go", "build", "-o", filepath.Join(dir, name), "../"+name).CombinedOutput()
+		require.NoError(t, err, string(out))
+	}
+
+	results := filepath.Join(dir, "results.json")
+	require.NoError(t, runLocal([]string{
+		"-scenario", "echo",
+		"-d", "3s",
+		"-c", "2",
+		"-o", results,
+		"-worker-bin", filepath.Join(dir, "worker"),
+		"-runner-bin", filepath.Join(dir, "runner"),
+	}))
+
+	b, err := os.ReadFile(results)
+	require.NoError(t, err)
+	var r struct {
+		Targets []struct {
+			WorkflowType string `json:"workflowType"`
+			Completed    uint64 `json:"completed"`
+		} `json:"targets"`
+	}
+	require.NoError(t, json.Unmarshal(b, &r))
+	require.Len(t, r.Targets, 1)
+	require.Equal(t, "ExecuteActivity", r.Targets[0].WorkflowType)
+	require.NotZero(t, r.Targets[0].Completed)
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+)
+
+// scenario describes a named workload the runner can be pointed at.
+type scenario struct {
+	Description  string
+	WorkflowType string
+	SignalType   string
+	Input        []string
+}
+
+var scenarios = map[string]scenario{
+	"echo": {
+		Description:  "ExecuteActivity running the Echo activity 3 times",
+		WorkflowType: "ExecuteActivity",
+		Input:        []string{`{"Count": 3, "Activity": "Echo", "Input": {"Message": "test"}}`},
+	},
+	"sleep": {
+		Description:  "ExecuteActivity running a 1 second Sleep activity",
+		WorkflowType: "ExecuteActivity",
+		Input:        []string{`{"Count": 1, "Activity": "Sleep", "Input": {"SleepTimeInSeconds": 1}}`},
+	},
+	"signal": {
+		Description:  "ReceiveSignal started with SignalWithStart",
+		WorkflowType: "ReceiveSignal",
+		SignalType:   "signal",
+		Input:        []string{`{"Count": 1, "Name": "signal"}`},
+	},
+	"dsl": {
+		Description:  "DSL workflow with repeated activities and a child workflow",
+		WorkflowType: "DSL",
+		Input:        []string{`[{"a": "Echo", "i": {"Message": "test"}, "r": 3}, {"c": [{"a": "Echo", "i": {"Message": "test"}, "r": 3}]}]`},
+	},
+	"padding": {
+		Description:  "DSL workflow with padded activity inputs to inflate history",
+		WorkflowType: "DSL",
+		Input:        []string{`[{"a": "Echo", "i": {"Message": "test"}, "p": 1024, "r": 5}]`},
+	},
+}
+
+func printScenarios() {
+	names := make([]string, 0, len(scenarios))
+	for name := range scenarios {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		fmt.Fprintf(os.Stdout, "  %-10s %s\n", name, scenarios[name].Description)
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// joinCoordinator registers with the coordinator and waits for this agent's
+// assignment, retrying until the coordinator is reachable.
+func joinCoordinator(ctx context.Context, url, agent string) (assignment, error) {
+	body, err := json.Marshal(map[string]string{"agent": agent})
+	if err != nil {
+		return assignment{}, err
+	}
+
+	for {
+		a, err := register(ctx, url, body)
+		if err == nil {
+			return a, nil
+		}
+		log.Printf("Unable to register with coordinator, retrying: %v", err)
+
+		select {
+		case <-time.After(2 * time.Second):
+		case <-ctx.Done():
+			return assignment{}, ctx.Err()
+		}
+	}
+}
+
+func register(ctx context.Context, url string, body []byte) (assignment, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(url, "/")+"/v1/register", bytes.NewReader(body))
+	if err != nil {
+		return assignment{}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return assignment{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return assignment{}, fmt.Errorf("coordinator returned %s", resp.Status)
+	}
+
+	var a assignment
+	err = json.NewDecoder(resp.Body).Decode(&a)
+	return a, err
+}
+
+// sendReport posts this agent's results to the coordinator.
+func sendReport(ctx context.Context, url string, report agentReport) error {
+	body, err := json.Marshal(report)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(url, "/")+"/v1/report", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent {
+		return fmt.Errorf("coordinator returned %s", resp.Status)
+	}
+	return nil
+}
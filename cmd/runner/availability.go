@@ -0,0 +1,207 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// minOutage is the shortest run of failed starts reported as an outage, so
+// that isolated errors under load don't drown out real unavailability.
+const minOutage = time.Second
+
+// The impact of a failover compares the mean completion latency over the
+// failoverBaseline before it to the worst one second mean over the
+// failoverWindow after it. Completion latency is kept for latencyRetention,
+// long enough to measure the baseline of a failover noticed late.
+const (
+	failoverBaseline = time.Minute
+	failoverWindow   = 2 * time.Minute
+	latencyRetention = 2 * time.Minute
+)
+
+// outageWindow is a period during which every workflow start failed.
+type outageWindow struct {
+	Start        time.Time `json:"start"`
+	End          time.Time `json:"end"`
+	Duration     string    `json:"duration"`
+	FailedStarts uint64    `json:"failedStarts"`
+}
+
+// failoverImpact compares completion latency before and after a failover.
+type failoverImpact struct {
+	failoverEvent
+	BaselineLatency string `json:"baselineLatency"`
+	PeakLatency     string `json:"peakLatency"`
+}
+
+type latencyBucket struct {
+	count uint64
+	total time.Duration
+}
+
+func (b latencyBucket) mean() time.Duration {
+	if b.count == 0 {
+		return 0
+	}
+	return b.total / time.Duration(b.count)
+}
+
+// trackedFailover is a failover whose impact is being measured. peak covers
+// the seconds of its window that have been pruned from the tracker.
+type trackedFailover struct {
+	event    failoverEvent
+	baseline latencyBucket
+	peak     time.Duration
+}
+
+// inWindow reports whether the unix second s is in the failover's window.
+func (t *trackedFailover) inWindow(s int64) bool {
+	at := t.event.Time.Unix()
+	return s >= at && s <= at+int64(failoverWindow/time.Second)
+}
+
+// availabilityTracker records start outcomes and completion latency over time
+// so that outages and latency spikes can be reported after the run.
+type availabilityTracker struct {
+	mu             sync.Mutex
+	outageStart    time.Time
+	outageFailures uint64
+	outages        []outageWindow
+	// latency holds the last latencyRetention of completion latency in one
+	// second buckets, keyed by the unix second the execution completed in.
+	latency   map[int64]latencyBucket
+	failovers []*trackedFailover
+}
+
+func newAvailabilityTracker() *availabilityTracker {
+	return &availabilityTracker{latency: make(map[int64]latencyBucket)}
+}
+
+func (a *availabilityTracker) startFailed(at time.Time) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.outageStart.IsZero() {
+		a.outageStart = at
+	}
+	a.outageFailures++
+}
+
+func (a *availabilityTracker) startSucceeded(at time.Time) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.closeOutage(at)
+}
+
+func (a *availabilityTracker) closeOutage(at time.Time) {
+	if a.outageStart.IsZero() {
+		return
+	}
+	if d := at.Sub(a.outageStart); d >= minOutage {
+		a.outages = append(a.outages, outageWindow{
+			Start:        a.outageStart,
+			End:          at,
+			Duration:     d.Round(time.Millisecond).String(),
+			FailedStarts: a.outageFailures,
+		})
+	}
+	a.outageStart = time.Time{}
+	a.outageFailures = 0
+}
+
+func (a *availabilityTracker) completed(at time.Time, latency time.Duration) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	b := a.latency[at.Unix()]
+	b.count++
+	b.total += latency
+	a.latency[at.Unix()] = b
+
+	a.prune(at)
+}
+
+// prune drops latency older than latencyRetention, first folding it into the
+// peaks of the failovers whose window it falls in.
+func (a *availabilityTracker) prune(now time.Time) {
+	oldest := now.Add(-latencyRetention).Unix()
+	for s, b := range a.latency {
+		if s >= oldest {
+			continue
+		}
+		for _, f := range a.failovers {
+			if f.inWindow(s) {
+				f.peak = max(f.peak, b.mean())
+			}
+		}
+		delete(a.latency, s)
+	}
+}
+
+// failedOver starts measuring the impact of a failover, taking its baseline
+// from the latency before it. It does nothing if the failover is already
+// being measured.
+func (a *availabilityTracker) failedOver(f failoverEvent) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.track(f)
+}
+
+func (a *availabilityTracker) track(f failoverEvent) *trackedFailover {
+	for _, t := range a.failovers {
+		if t.event == f {
+			return t
+		}
+	}
+
+	t := &trackedFailover{event: f}
+	at := f.Time.Unix()
+	for s := at - int64(failoverBaseline/time.Second); s < at; s++ {
+		b := a.latency[s]
+		t.baseline.count += b.count
+		t.baseline.total += b.total
+	}
+	a.failovers = append(a.failovers, t)
+	return t
+}
+
+// outageWindows returns the outages seen, treating one still in progress as
+// ending at end.
+func (a *availabilityTracker) outageWindows(end time.Time) []outageWindow {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	outages := append([]outageWindow(nil), a.outages...)
+	if !a.outageStart.IsZero() && end.Sub(a.outageStart) >= minOutage {
+		outages = append(outages, outageWindow{
+			Start:        a.outageStart,
+			End:          end,
+			Duration:     end.Sub(a.outageStart).Round(time.Millisecond).String(),
+			FailedStarts: a.outageFailures,
+		})
+	}
+	return outages
+}
+
+// impact reports the mean completion latency in the minute before a failover
+// and the worst one second mean latency in the two minutes after it.
+func (a *availabilityTracker) impact(f failoverEvent) failoverImpact {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	t := a.track(f)
+	peak := t.peak
+	for s, b := range a.latency {
+		if t.inWindow(s) {
+			peak = max(peak, b.mean())
+		}
+	}
+
+	impact := failoverImpact{failoverEvent: f, PeakLatency: peak.String()}
+	if t.baseline.count > 0 {
+		impact.BaselineLatency = t.baseline.mean().String()
+	}
+	return impact
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAvailabilityTrackerOutages(t *testing.T) {
+	a := newAvailabilityTracker()
+	base := time.Unix(1000, 0)
+
+	// A brief blip is not reported.
+	a.startFailed(base)
+	a.startSucceeded(base.Add(100 * time.Millisecond))
+
+	a.startFailed(base.Add(10 * time.Second))
+	a.startFailed(base.Add(11 * time.Second))
+	a.startFailed(base.Add(12 * time.Second))
+	a.startSucceeded(base.Add(13 * time.Second))
+
+	// Still failing when the run ends.
+	a.startFailed(base.Add(20 * time.Second))
+
+	outages := a.outageWindows(base.Add(25 * time.Second))
+	require.Len(t, outages, 2)
+	require.Equal(t, base.Add(10*time.Second), outages[0].Start)
+	require.Equal(t, "3s", outages[0].Duration)
+	require.Equal(t, uint64(3), outages[0].FailedStarts)
+	require.Equal(t, "5s", outages[1].Duration)
+	require.Equal(t, uint64(1), outages[1].FailedStarts)
+}
+
+func TestAvailabilityTrackerImpact(t *testing.T) {
+	a := newAvailabilityTracker()
+	failover := time.Unix(1000, 0)
+
+	for i := 1; i <= 30; i++ {
+		a.completed(failover.Add(-time.Duration(i)*time.Second), 100*time.Millisecond)
+	}
+	a.completed(failover.Add(5*time.Second), 2*time.Second)
+	a.completed(failover.Add(5*time.Second), 4*time.Second)
+	a.completed(failover.Add(10*time.Second), 200*time.Millisecond)
+
+	impact := a.impact(failoverEvent{Time: failover, From: "a", To: "b"})
+	require.Equal(t, "100ms", impact.BaselineLatency)
+	require.Equal(t, "3s", impact.PeakLatency)
+}
+
+func TestAvailabilityTrackerBounded(t *testing.T) {
+	a := newAvailabilityTracker()
+	failover := time.Unix(1000, 0)
+	f := failoverEvent{Time: failover, From: "a", To: "b"}
+
+	for i := 1; i <= 30; i++ {
+		a.completed(failover.Add(-time.Duration(i)*time.Second), 100*time.Millisecond)
+	}
+	a.failedOver(f)
+	a.completed(failover.Add(5*time.Second), 3*time.Second)
+
+	// An hour later only the last latencyRetention is kept, but the impact of
+	// the failover is still known.
+	for i := 6; i < 3600; i++ {
+		a.completed(failover.Add(time.Duration(i)*time.Second), 100*time.Millisecond)
+	}
+	require.LessOrEqual(t, len(a.latency), int(latencyRetention/time.Second)+1)
+
+	impact := a.impact(f)
+	require.Equal(t, "100ms", impact.BaselineLatency)
+	require.Equal(t, "3s", impact.PeakLatency)
+}
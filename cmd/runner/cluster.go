@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.temporal.io/api/serviceerror"
+	"go.temporal.io/api/workflowservice/v1"
+	"go.temporal.io/sdk/client"
+)
+
+// cluster is a Temporal cluster endpoint the runner can send load to.
+type cluster struct {
+	Name     string
+	Endpoint string
+	client   client.Client
+}
+
+// failoverEvent records the active cluster of a namespace changing.
+type failoverEvent struct {
+	Time time.Time `json:"time"`
+	From string    `json:"from"`
+	To   string    `json:"to"`
+}
+
+// clusterClient hands out a client for the currently active cluster of a
+// namespace. With more than one cluster it follows namespace failovers, both
+// by polling DescribeNamespace and by reacting to NamespaceNotActive errors.
+type clusterClient struct {
+	namespace string
+	clusters  []*cluster
+	active    atomic.Pointer[cluster]
+
+	mu        sync.Mutex
+	failovers []failoverEvent
+	// changed is closed, and replaced, when the active cluster changes.
+	changed chan struct{}
+}
+
+func newClusterClient(namespace string, clusters []*cluster) *clusterClient {
+	cc := &clusterClient{
+		namespace: namespace,
+		clusters:  clusters,
+		changed:   make(chan struct{}),
+	}
+	cc.active.Store(clusters[0])
+	return cc
+}
+
+// Client returns the client for the active cluster.
+func (cc *clusterClient) Client() client.Client {
+	return cc.active.Load().client
+}
+
+// Clusters returns every cluster, active or not.
+func (cc *clusterClient) Clusters() []*cluster {
+	return cc.clusters
+}
+
+// Failovers returns the failovers observed so far.
+func (cc *clusterClient) Failovers() []failoverEvent {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+
+	return append([]failoverEvent(nil), cc.failovers...)
+}
+
+func (cc *clusterClient) setActive(name string) {
+	current := cc.active.Load()
+	if name == "" || current.Name == name {
+		return
+	}
+
+	for _, cl := range cc.clusters {
+		if cl.Name != name {
+			continue
+		}
+		if !cc.active.CompareAndSwap(current, cl) {
+			return
+		}
+
+		cc.mu.Lock()
+		cc.failovers = append(cc.failovers, failoverEvent{Time: time.Now(), From: current.Name, To: name})
+		close(cc.changed)
+		cc.changed = make(chan struct{})
+		cc.mu.Unlock()
+
+		log.Printf("Namespace %s failed over from %s to %s (%s)", cc.namespace, current.Name, name, cl.Endpoint)
+		return
+	}
+
+	log.Printf("Namespace %s is active in unknown cluster %s", cc.namespace, name)
+}
+
+// activeChanged returns a channel that is closed when the active cluster next
+// changes.
+func (cc *clusterClient) activeChanged() <-chan struct{} {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+
+	return cc.changed
+}
+
+// handleError switches cluster if err says the namespace is active elsewhere.
+func (cc *clusterClient) handleError(err error) {
+	var notActive *serviceerror.NamespaceNotActive
+	if errors.As(err, &notActive) {
+		cc.setActive(notActive.ActiveCluster)
+	}
+}
+
+// refresh asks each cluster in turn, starting with the active one, where the
+// namespace is currently active.
+func (cc *clusterClient) refresh(ctx context.Context) {
+	candidates := append([]*cluster{cc.active.Load()}, cc.clusters...)
+
+	for _, cl := range candidates {
+		reqCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		resp, err := cl.client.WorkflowService().DescribeNamespace(reqCtx, &workflowservice.DescribeNamespaceRequest{
+			Namespace: cc.namespace,
+		})
+		cancel()
+		if err != nil {
+			continue
+		}
+
+		cc.setActive(resp.GetReplicationConfig().GetActiveClusterName())
+		return
+	}
+}
+
+// watch refreshes the active cluster every interval until ctx is done. It is
+// a no-op with a single cluster.
+func (cc *clusterClient) watch(ctx context.Context, interval time.Duration) {
+	if len(cc.clusters) < 2 {
+		return
+	}
+
+	cc.refresh(ctx)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			cc.refresh(ctx)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// clusterName looks up the name a cluster reports for itself.
+func clusterName(ctx context.Context, c client.Client) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	resp, err := c.WorkflowService().GetClusterInfo(ctx, &workflowservice.GetClusterInfoRequest{})
+	if err != nil {
+		return "", err
+	}
+	return resp.GetClusterName(), nil
+}
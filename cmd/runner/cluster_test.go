@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/uber-go/tally/v4"
+	"go.temporal.io/api/serviceerror"
+	"go.temporal.io/sdk/client"
+)
+
+// fakeClusterClient hands out runs whose Get fails or blocks as the test says.
+type fakeClusterClient struct {
+	client.Client
+	get func(ctx context.Context) error
+}
+
+func (c *fakeClusterClient) GetWorkflow(_ context.Context, workflowID, runID string) client.WorkflowRun {
+	return &fakeRun{id: workflowID, runID: runID, get: c.get}
+}
+
+type fakeRun struct {
+	client.WorkflowRun
+	id, runID string
+	get       func(ctx context.Context) error
+}
+
+func (r *fakeRun) GetID() string    { return r.id }
+func (r *fakeRun) GetRunID() string { return r.runID }
+
+func (r *fakeRun) Get(ctx context.Context, _ interface{}) error {
+	return r.get(ctx)
+}
+
+func TestResultFollowsFailover(t *testing.T) {
+	notActive := func(context.Context) error {
+		return serviceerror.NewNamespaceNotActive("default", "a", "b")
+	}
+	blocked := func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	fetchedFromB := make(chan struct{}, 1)
+	fromB := func(context.Context) error {
+		fetchedFromB <- struct{}{}
+		return nil
+	}
+
+	t.Run("get fails with namespace not active", func(t *testing.T) {
+		clients := newClusterClient("default", []*cluster{
+			{Name: "a", client: &fakeClusterClient{get: notActive}},
+			{Name: "b", client: &fakeClusterClient{get: fromB}},
+		})
+		g := newLoadGenerator(target{Concurrency: 1}, clients, workload{}, backoffOptions{}, tally.NoopScope)
+
+		run := clients.Client().GetWorkflow(context.Background(), "wf", "run")
+		require.NoError(t, g.result(context.Background(), run))
+		require.Len(t, fetchedFromB, 1)
+		<-fetchedFromB
+		require.Len(t, clients.Failovers(), 1)
+	})
+
+	t.Run("failover while get is waiting", func(t *testing.T) {
+		clients := newClusterClient("default", []*cluster{
+			{Name: "a", client: &fakeClusterClient{get: blocked}},
+			{Name: "b", client: &fakeClusterClient{get: fromB}},
+		})
+		g := newLoadGenerator(target{Concurrency: 1}, clients, workload{}, backoffOptions{}, tally.NoopScope)
+
+		done := make(chan error, 1)
+		go func() {
+			done <- g.result(context.Background(), clients.Client().GetWorkflow(context.Background(), "wf", "run"))
+		}()
+		time.Sleep(50 * time.Millisecond)
+		clients.setActive("b")
+
+		select {
+		case err := <-done:
+			require.NoError(t, err)
+		case <-time.After(5 * time.Second):
+			t.Fatal("result did not follow the failover")
+		}
+		<-fetchedFromB
+	})
+
+	t.Run("other errors are returned", func(t *testing.T) {
+		clients := newClusterClient("default", []*cluster{
+			{Name: "a", client: &fakeClusterClient{get: blocked}},
+		})
+		g := newLoadGenerator(target{Concurrency: 1}, clients, workload{}, backoffOptions{}, tally.NoopScope)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		require.ErrorIs(t, g.result(ctx, clients.Client().GetWorkflow(ctx, "wf", "run")), context.DeadlineExceeded)
+	})
+}
+
+func TestActiveChanged(t *testing.T) {
+	clients := newClusterClient("default", []*cluster{{Name: "a"}, {Name: "b"}})
+	changed := clients.activeChanged()
+
+	clients.setActive("a")
+	select {
+	case <-changed:
+		t.Fatal("closed without a change")
+	default:
+	}
+
+	clients.setActive("b")
+	<-changed
+	require.NotEqual(t, changed, clients.activeChanged())
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Phases a run moves through, as reported by the status API.
+const (
+	phaseWaiting  = "waiting"
+	phaseRunning  = "running"
+	phasePaused   = "paused"
+	phaseStopping = "stopping"
+	phaseFinished = "finished"
+)
+
+// rateWindow is the window over which the status API reports current rates.
+const rateWindow = 10 * time.Second
+
+// targetStatus is the live state of one target.
+type targetStatus struct {
+	Label            string                    `json:"label"`
+	Paused           bool                      `json:"paused"`
+	Concurrency      int                       `json:"concurrency"`
+	TargetRate       float64                   `json:"targetRate"`
+	StartRate        float64                   `json:"startRate"`
+	CompletionRate   float64                   `json:"completionRate"`
+	InFlight         int                       `json:"inFlight"`
+	Started          uint64                    `json:"started"`
+	StartFailed      uint64                    `json:"startFailed"`
+	Completed        uint64                    `json:"completed"`
+	Failed           uint64                    `json:"failed"`
+	Latency          latencySummary            `json:"latency"`
+	CorrectedLatency latencySummary            `json:"correctedLatency"`
+	Errors           map[string]uint64         `json:"errors,omitempty"`
+	TaskQueueStats   map[string]taskQueueStats `json:"taskQueueStats,omitempty"`
+}
+
+// runnerStatus is the live state of the run, served by the status API.
+type runnerStatus struct {
+	Phase   string         `json:"phase"`
+	Elapsed string         `json:"elapsed"`
+	Targets []targetStatus `json:"targets"`
+}
+
+// controller exposes the state of a run and lets it be changed while it is
+// running.
+type controller struct {
+	generators []*loadGenerator
+	stop       context.CancelFunc
+
+	mu        sync.Mutex
+	phase     string
+	startTime time.Time
+}
+
+func newController(generators []*loadGenerator, stop context.CancelFunc) *controller {
+	return &controller{
+		generators: generators,
+		stop:       stop,
+		phase:      phaseWaiting,
+	}
+}
+
+func (c *controller) setPhase(phase string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if phase == phaseRunning && c.startTime.IsZero() {
+		c.startTime = time.Now()
+	}
+	c.phase = phase
+}
+
+func (c *controller) status() runnerStatus {
+	c.mu.Lock()
+	status := runnerStatus{Phase: c.phase}
+	if !c.startTime.IsZero() {
+		status.Elapsed = time.Since(c.startTime).Round(time.Second).String()
+	}
+	c.mu.Unlock()
+
+	for _, g := range c.generators {
+		limit, used, paused := g.slots.state()
+		startRate, completionRate := g.history.rates(rateWindow)
+
+		var queues map[string]taskQueueStats
+		if g.queues != nil {
+			queues = g.queues.stats()
+		}
+
+		status.Targets = append(status.Targets, targetStatus{
+			Label:            g.target.Label,
+			Paused:           paused,
+			Concurrency:      limit,
+			TargetRate:       g.currentRate(),
+			StartRate:        startRate,
+			CompletionRate:   completionRate,
+			InFlight:         used,
+			Started:          g.started.Load(),
+			StartFailed:      g.startFailed.Load(),
+			Completed:        g.completed.Load(),
+			Failed:           g.failed.Load(),
+			Latency:          g.latency.summary(),
+			CorrectedLatency: g.corrected.summary(),
+			Errors:           g.errorClasses(),
+			TaskQueueStats:   queues,
+		})
+	}
+
+	return status
+}
+
+// selected returns the generators a command applies to: the one named by the
+// target query parameter, or all of them.
+func (c *controller) selected(r *http.Request) ([]*loadGenerator, error) {
+	label := r.URL.Query().Get("target")
+	if label == "" {
+		return c.generators, nil
+	}
+	for _, g := range c.generators {
+		if g.target.Label == label {
+			return []*loadGenerator{g}, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown target %q", label)
+}
+
+func (c *controller) setPaused(paused bool) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		generators, err := c.selected(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+
+		for _, g := range generators {
+			g.setPaused(paused)
+			log.Printf("Target %s paused: %t", g.target.Label, paused)
+		}
+
+		c.mu.Lock()
+		if c.phase == phaseRunning || c.phase == phasePaused {
+			c.phase = phaseRunning
+			if c.allPaused() {
+				c.phase = phasePaused
+			}
+		}
+		c.mu.Unlock()
+
+		writeJSON(w, c.status())
+	}
+}
+
+func (c *controller) allPaused() bool {
+	for _, g := range c.generators {
+		if _, _, paused := g.slots.state(); !paused {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *controller) handleConcurrency(w http.ResponseWriter, r *http.Request) {
+	n, err := strconv.Atoi(r.URL.Query().Get("value"))
+	if err != nil || n <= 0 {
+		http.Error(w, "value must be a positive integer", http.StatusBadRequest)
+		return
+	}
+	generators, err := c.selected(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	for _, g := range generators {
+		g.setConcurrency(n)
+		log.Printf("Target %s concurrency set to %d", g.target.Label, n)
+	}
+	writeJSON(w, c.status())
+}
+
+func (c *controller) handleRate(w http.ResponseWriter, r *http.Request) {
+	v, err := strconv.ParseFloat(r.URL.Query().Get("value"), 64)
+	if err != nil || v < 0 {
+		http.Error(w, "value must be a non-negative number", http.StatusBadRequest)
+		return
+	}
+	generators, err := c.selected(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	for _, g := range generators {
+		g.setRate(v)
+		log.Printf("Target %s rate set to %.2f/s", g.target.Label, v)
+	}
+	writeJSON(w, c.status())
+}
+
+func (c *controller) handleStop(w http.ResponseWriter, r *http.Request) {
+	log.Printf("Stop requested")
+	c.setPhase(phaseStopping)
+	c.stop()
+	writeJSON(w, c.status())
+}
+
+// serve runs the control API on addr until ctx is done.
+func (c *controller) serve(ctx context.Context, addr string) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /status", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, c.status())
+	})
+	mux.HandleFunc("POST /pause", c.setPaused(true))
+	mux.HandleFunc("POST /resume", c.setPaused(false))
+	mux.HandleFunc("POST /concurrency", c.handleConcurrency)
+	mux.HandleFunc("POST /rate", c.handleRate)
+	mux.HandleFunc("POST /stop", c.handleStop)
+
+	server := &http.Server{Addr: addr, Handler: mux}
+	go func() {
+		<-ctx.Done()
+		server.Shutdown(context.Background())
+	}()
+
+	log.Printf("Control API listening on %s", addr)
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Fatalf("Control API failed: %v", err)
+	}
+}
@@ -0,0 +1,331 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/HdrHistogram/hdrhistogram-go"
+)
+
+// coordinatorStartDelay is how far in the future agents are told to start,
+// giving every agent time to receive its assignment.
+const coordinatorStartDelay = 5 * time.Second
+
+// assignment is the share of a run the coordinator hands to one agent.
+type assignment struct {
+	Agent    int           `json:"agent"`
+	Agents   int           `json:"agents"`
+	Targets  []target      `json:"targets"`
+	Workload workload      `json:"workload"`
+	StartAt  time.Time     `json:"startAt"`
+	Duration time.Duration `json:"duration"`
+}
+
+// agentReport is what an agent sends back to the coordinator after its run.
+type agentReport struct {
+	Agent      string                            `json:"agent"`
+	Results    runResults                        `json:"results"`
+	Histograms map[string]*hdrhistogram.Snapshot `json:"histograms"`
+	// CorrectedHistograms hold each target's coordinated-omission-corrected
+	// latencies.
+	CorrectedHistograms map[string]*hdrhistogram.Snapshot `json:"correctedHistograms"`
+	// ReadHistograms hold the latency of each target's reads, by operation.
+	ReadHistograms map[string]map[string]*hdrhistogram.Snapshot `json:"readHistograms,omitempty"`
+}
+
+// coordinator distributes a run across a fixed number of agents, starts them
+// together and aggregates their results.
+type coordinator struct {
+	agents       int
+	shardTargets bool
+	targets      []target
+	workload     workload
+	duration     time.Duration
+
+	mu         sync.Mutex
+	registered []string
+	startAt    time.Time
+	ready      chan struct{}
+	reports    map[string]agentReport
+	done       chan struct{}
+}
+
+func newCoordinator(agents int, shardTargets bool, targets []target, w workload, duration time.Duration) *coordinator {
+	return &coordinator{
+		agents:       agents,
+		shardTargets: shardTargets,
+		targets:      targets,
+		workload:     w,
+		duration:     duration,
+		ready:        make(chan struct{}),
+		reports:      make(map[string]agentReport),
+		done:         make(chan struct{}),
+	}
+}
+
+// splitTargets returns the targets agent index of n should drive. Targets are
+// either dealt out whole, or each target's concurrency and rate are split
+// between agents.
+func splitTargets(targets []target, n, index int, shard bool) []target {
+	var share []target
+	for i, t := range targets {
+		if shard {
+			if i%n == index {
+				share = append(share, t)
+			}
+			continue
+		}
+
+		t.Concurrency = t.Concurrency / n
+		if index < targets[i].Concurrency%n {
+			t.Concurrency++
+		}
+		if t.Concurrency == 0 {
+			continue
+		}
+		// With fewer workflows than agents, some agents get none of this
+		// target and the rate is split between the rest.
+		sharing := float64(min(n, targets[i].Concurrency))
+		t.Rate = t.Rate / sharing
+		t.ReadRate = t.ReadRate / sharing
+		share = append(share, t)
+	}
+	return share
+}
+
+// splitRamps returns the ramp steps agent index of n should apply. Each step
+// is applied by the first agent that drives a target in its namespace.
+func splitRamps(steps []rampStep, targets []target, n, index int, shard bool) []rampStep {
+	owners := make(map[string]int)
+	for i := n - 1; i >= 0; i-- {
+		for _, t := range splitTargets(targets, n, i, shard) {
+			owners[t.Namespace] = i
+		}
+	}
+
+	var share []rampStep
+	for _, s := range steps {
+		if owner, ok := owners[s.Namespace]; ok && owner == index {
+			share = append(share, s)
+		}
+	}
+	return share
+}
+
+func (co *coordinator) handleRegister(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Agent string `json:"agent"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Agent == "" {
+		http.Error(w, "agent name required", http.StatusBadRequest)
+		return
+	}
+
+	co.mu.Lock()
+	index := -1
+	for i, name := range co.registered {
+		if name == req.Agent {
+			index = i
+		}
+	}
+	if index < 0 {
+		if len(co.registered) == co.agents {
+			co.mu.Unlock()
+			http.Error(w, "all agents already registered", http.StatusConflict)
+			return
+		}
+		index = len(co.registered)
+		co.registered = append(co.registered, req.Agent)
+		log.Printf("Agent %s registered (%d/%d)", req.Agent, len(co.registered), co.agents)
+
+		if len(co.registered) == co.agents {
+			co.startAt = time.Now().Add(coordinatorStartDelay)
+			close(co.ready)
+		}
+	}
+	co.mu.Unlock()
+
+	select {
+	case <-co.ready:
+	case <-r.Context().Done():
+		return
+	}
+
+	wl := co.workload
+	wl.Ramps = splitRamps(co.workload.Ramps, co.targets, co.agents, index, co.shardTargets)
+	writeJSON(w, assignment{
+		Agent:    index,
+		Agents:   co.agents,
+		Targets:  splitTargets(co.targets, co.agents, index, co.shardTargets),
+		Workload: wl,
+		StartAt:  co.startAt,
+		Duration: co.duration,
+	})
+}
+
+func (co *coordinator) handleReport(w http.ResponseWriter, r *http.Request) {
+	var report agentReport
+	if err := json.NewDecoder(r.Body).Decode(&report); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	co.mu.Lock()
+	defer co.mu.Unlock()
+
+	if _, ok := co.reports[report.Agent]; ok {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	co.reports[report.Agent] = report
+	log.Printf("Agent %s reported (%d/%d)", report.Agent, len(co.reports), co.agents)
+
+	if len(co.reports) == co.agents {
+		close(co.done)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// aggregate merges the reports received so far into a single result, summing
+// counters and merging latency histograms per target.
+func (co *coordinator) aggregate() runResults {
+	co.mu.Lock()
+	defer co.mu.Unlock()
+
+	results := runResults{Duration: co.duration.String()}
+
+	for _, t := range co.targets {
+		agg := targetResults{
+			Label:               t.Label,
+			WorkflowType:        co.workload.WorkflowType,
+			Namespace:           t.Namespace,
+			TaskQueue:           t.TaskQueue,
+			ConcurrentWorkflows: t.Concurrency,
+			TargetRate:          t.Rate,
+			TargetReadRate:      t.ReadRate,
+			Errors:              make(map[string]uint64),
+		}
+		latency := newLatencyHistogram()
+		corrected := newLatencyHistogram()
+		var histories []*historySummary
+		reads := make(map[string]readSummary)
+		readLatency := make(map[string]*latencyHistogram)
+		queues := make(map[string]taskQueueSummary)
+
+		for _, report := range co.reports {
+			for _, r := range report.Results.Targets {
+				if r.Label != t.Label {
+					continue
+				}
+				agg.Started += r.Started
+				agg.StartFailed += r.StartFailed
+				agg.Completed += r.Completed
+				agg.Failed += r.Failed
+				agg.Rate += r.Rate
+				agg.Outages = append(agg.Outages, r.Outages...)
+				agg.Failovers = append(agg.Failovers, r.Failovers...)
+				for class, n := range r.Errors {
+					agg.Errors[class] += n
+				}
+				histories = append(histories, r.History)
+				for op, rs := range r.Reads {
+					sum := reads[op]
+					sum.Calls += rs.Calls
+					sum.Errors += rs.Errors
+					sum.Rate += rs.Rate
+					sum.TotalBytes += rs.TotalBytes
+					reads[op] = sum
+				}
+				for tqType, s := range r.TaskQueueStats {
+					queues[tqType] = queues[tqType].merge(s)
+				}
+			}
+			if s, ok := report.Histograms[t.Label]; ok {
+				latency.merge(s)
+			}
+			if s, ok := report.CorrectedHistograms[t.Label]; ok {
+				corrected.merge(s)
+			}
+			for op, s := range report.ReadHistograms[t.Label] {
+				if readLatency[op] == nil {
+					readLatency[op] = newLatencyHistogram()
+				}
+				readLatency[op].merge(s)
+			}
+		}
+
+		agg.Latency = latency.summary()
+		agg.CorrectedLatency = corrected.summary()
+		agg.History = mergeHistorySummaries(histories)
+		for op, rs := range reads {
+			if ok := rs.Calls - rs.Errors; ok > 0 {
+				rs.MeanBytes = float64(rs.TotalBytes) / float64(ok)
+			}
+			if l := readLatency[op]; l != nil {
+				rs.Latency = l.summary()
+			}
+			if agg.Reads == nil {
+				agg.Reads = make(map[string]readSummary)
+			}
+			agg.Reads[op] = rs
+		}
+		if len(queues) > 0 {
+			agg.TaskQueueStats = queues
+		}
+		results.Targets = append(results.Targets, agg)
+	}
+
+	// Each ramp step is applied by one agent.
+	for _, report := range co.reports {
+		results.Ramps = append(results.Ramps, report.Results.Ramps...)
+	}
+	slices.SortFunc(results.Ramps, func(a, b rampEvent) int {
+		return a.Time.Compare(b.Time)
+	})
+
+	return results
+}
+
+// run serves agents until they have all reported or ctx is done, and returns
+// the aggregated results.
+func (co *coordinator) run(ctx context.Context, addr string) (runResults, error) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /v1/register", co.handleRegister)
+	mux.HandleFunc("POST /v1/report", co.handleReport)
+
+	server := &http.Server{Addr: addr, Handler: mux}
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- server.ListenAndServe()
+	}()
+	defer server.Shutdown(context.Background())
+
+	log.Printf("Coordinator listening on %s, waiting for %d agents", addr, co.agents)
+
+	select {
+	case <-co.done:
+		log.Printf("All agents reported")
+	case <-ctx.Done():
+		co.mu.Lock()
+		log.Printf("Stopping with %d/%d agent reports", len(co.reports), co.agents)
+		co.mu.Unlock()
+	case err := <-errCh:
+		if !errors.Is(err, http.ErrServerClosed) {
+			return runResults{}, fmt.Errorf("coordinator server failed: %w", err)
+		}
+	}
+
+	return co.aggregate(), nil
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
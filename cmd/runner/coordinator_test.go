@@ -0,0 +1,60 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitTargets(t *testing.T) {
+	targets := []target{
+		{Label: "a", Concurrency: 10, Rate: 30, ReadRate: 6},
+		{Label: "b", Concurrency: 2},
+		{Label: "c", Concurrency: 1},
+	}
+
+	require.Equal(t, []target{
+		{Label: "a", Concurrency: 4, Rate: 10, ReadRate: 2},
+		{Label: "b", Concurrency: 1},
+		{Label: "c", Concurrency: 1},
+	}, splitTargets(targets, 3, 0, false))
+	require.Equal(t, []target{
+		{Label: "a", Concurrency: 3, Rate: 10, ReadRate: 2},
+		{Label: "b", Concurrency: 1},
+	}, splitTargets(targets, 3, 1, false))
+	require.Equal(t, []target{
+		{Label: "a", Concurrency: 3, Rate: 10, ReadRate: 2},
+	}, splitTargets(targets, 3, 2, false))
+
+	// Only two of four agents get a share of two workflows, so they each take
+	// half the rate.
+	small := []target{{Label: "a", Concurrency: 2, Rate: 10, ReadRate: 4}}
+	for index := 0; index < 2; index++ {
+		require.Equal(t, []target{{Label: "a", Concurrency: 1, Rate: 5, ReadRate: 2}}, splitTargets(small, 4, index, false))
+	}
+	for index := 2; index < 4; index++ {
+		require.Empty(t, splitTargets(small, 4, index, false))
+	}
+
+	require.Equal(t, []target{targets[0], targets[2]}, splitTargets(targets, 2, 0, true))
+	require.Equal(t, []target{targets[1]}, splitTargets(targets, 2, 1, true))
+}
+
+func TestSplitRamps(t *testing.T) {
+	targets := []target{
+		{Label: "a", Namespace: "ns-a", Concurrency: 1},
+		{Label: "b", Namespace: "ns-b", Concurrency: 1},
+	}
+	steps := []rampStep{
+		{Namespace: "ns-a", BuildID: "v2", Current: true},
+		{Namespace: "ns-b", BuildID: "v2", Percentage: 10},
+	}
+
+	// Sharded, each namespace's steps go to the agent that drives it.
+	require.Equal(t, steps[:1], splitRamps(steps, targets, 2, 0, true))
+	require.Equal(t, steps[1:], splitRamps(steps, targets, 2, 1, true))
+
+	// Split, the first agent has a share of every target.
+	require.Equal(t, steps, splitRamps(steps, targets, 2, 0, false))
+	require.Empty(t, splitRamps(steps, targets, 2, 1, false))
+}
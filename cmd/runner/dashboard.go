@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// sparklineWidth is how many seconds of history the sparklines show.
+	sparklineWidth = 60
+	// maxMessages is how many recent log and error lines the dashboard shows.
+	maxMessages = 8
+)
+
+var sparkBlocks = []rune("▁▂▃▄▅▆▇█")
+
+// messageBuffer is an io.Writer that keeps the most recent lines written to
+// it, so log output can be shown inside the dashboard.
+type messageBuffer struct {
+	mu      sync.Mutex
+	lines   []string
+	partial string
+}
+
+func (b *messageBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	text := b.partial + string(p)
+	lines := strings.Split(text, "\n")
+	b.partial = lines[len(lines)-1]
+
+	b.lines = append(b.lines, lines[:len(lines)-1]...)
+	if len(b.lines) > maxMessages {
+		b.lines = b.lines[len(b.lines)-maxMessages:]
+	}
+	return len(p), nil
+}
+
+func (b *messageBuffer) recent() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return append([]string(nil), b.lines...)
+}
+
+// dashboard renders a live view of the run to a terminal once a second.
+type dashboard struct {
+	ctrl       *controller
+	generators []*loadGenerator
+	messages   *messageBuffer
+	out        io.Writer
+}
+
+// run draws the dashboard on the terminal's alternate screen until ctx is
+// done, then restores the screen.
+func (d *dashboard) run(ctx context.Context) {
+	// Switch to the alternate screen and hide the cursor.
+	fmt.Fprint(d.out, "\x1b[?1049h\x1b[?25l")
+	defer fmt.Fprint(d.out, "\x1b[?25h\x1b[?1049l")
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		fmt.Fprint(d.out, "\x1b[H\x1b[2J"+d.render())
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (d *dashboard) render() string {
+	var b strings.Builder
+
+	status := d.ctrl.status()
+	fmt.Fprintf(&b, "Benchmark runner  %s  %s\n\n", strings.ToUpper(status.Phase), status.Elapsed)
+
+	for i, t := range status.Targets {
+		g := d.generators[i]
+		series := g.history.series()
+
+		rateDesc := "unlimited"
+		if t.TargetRate > 0 {
+			rateDesc = fmt.Sprintf("%.1f/s", t.TargetRate)
+		}
+		paused := ""
+		if t.Paused {
+			paused = "  PAUSED"
+		}
+
+		fmt.Fprintf(&b, "%s%s\n", t.Label, paused)
+		fmt.Fprintf(&b, "  Concurrency %d  Target rate %s  In flight %d\n", t.Concurrency, rateDesc, t.InFlight)
+		fmt.Fprintf(&b, "  Starts/s      %9.1f  %s\n", t.StartRate, sparkline(series, func(s counterSample) uint64 { return s.Started }))
+		fmt.Fprintf(&b, "  Completions/s %9.1f  %s\n", t.CompletionRate, sparkline(series, func(s counterSample) uint64 { return s.Completed }))
+		fmt.Fprintf(&b, "  Started %d  Completed %d  Failed %d  Start failures %d\n", t.Started, t.Completed, t.Failed, t.StartFailed)
+		if t.Latency.Count > 0 {
+			fmt.Fprintf(&b, "  Latency p50 %s  p90 %s  p99 %s  p99.9 %s  max %s\n",
+				t.Latency.P50, t.Latency.P90, t.Latency.P99, t.Latency.P999, t.Latency.Max)
+		}
+		if t.CorrectedLatency.Count > 0 {
+			fmt.Fprintf(&b, "  Corrected p50 %s  p90 %s  p99 %s  p99.9 %s  max %s\n",
+				t.CorrectedLatency.P50, t.CorrectedLatency.P90, t.CorrectedLatency.P99, t.CorrectedLatency.P999, t.CorrectedLatency.Max)
+		}
+		for _, tqType := range taskQueueTypes {
+			if s, ok := t.TaskQueueStats[tqType]; ok {
+				fmt.Fprintf(&b, "  Task queue %s: %s\n", tqType, formatTaskQueueStats(s))
+			}
+		}
+		if len(t.Errors) > 0 {
+			fmt.Fprintf(&b, "  Errors %s\n", formatErrors(t.Errors))
+		}
+		b.WriteString("\n")
+	}
+
+	if lines := d.messages.recent(); len(lines) > 0 {
+		b.WriteString("Recent messages\n")
+		for _, line := range lines {
+			fmt.Fprintf(&b, "  %s\n", line)
+		}
+	}
+
+	return b.String()
+}
+
+// sparkline draws the per second change in a counter over the last
+// sparklineWidth samples.
+func sparkline(series []counterSample, value func(counterSample) uint64) string {
+	if len(series) > sparklineWidth+1 {
+		series = series[len(series)-sparklineWidth-1:]
+	}
+	if len(series) < 2 {
+		return ""
+	}
+
+	deltas := make([]uint64, len(series)-1)
+	var peak uint64
+	for i := 1; i < len(series); i++ {
+		deltas[i-1] = value(series[i]) - value(series[i-1])
+		peak = max(peak, deltas[i-1])
+	}
+
+	var b strings.Builder
+	for _, d := range deltas {
+		idx := 0
+		if peak > 0 {
+			idx = int(d * uint64(len(sparkBlocks)-1) / peak)
+		}
+		b.WriteRune(sparkBlocks[idx])
+	}
+	return b.String()
+}
+
+func formatErrors(counts map[string]uint64) string {
+	classes := make([]string, 0, len(counts))
+	for class := range counts {
+		classes = append(classes, class)
+	}
+	sort.Strings(classes)
+
+	parts := make([]string, len(classes))
+	for i, class := range classes {
+		parts[i] = fmt.Sprintf("%s %d", class, counts[class])
+	}
+	return strings.Join(parts, "  ")
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSparkline(t *testing.T) {
+	var series []counterSample
+	var total uint64
+	for _, delta := range []uint64{0, 0, 7, 14, 7, 0} {
+		total += delta
+		series = append(series, counterSample{Completed: total})
+	}
+
+	require.Equal(t, "▁▄█▄▁", sparkline(series, func(s counterSample) uint64 { return s.Completed }))
+	require.Equal(t, "", sparkline(series[:1], func(s counterSample) uint64 { return s.Completed }))
+}
+
+func TestMessageBuffer(t *testing.T) {
+	b := &messageBuffer{}
+
+	fmt.Fprint(b, "first\nsec")
+	fmt.Fprint(b, "ond\n")
+	require.Equal(t, []string{"first", "second"}, b.recent())
+
+	for i := 0; i < maxMessages; i++ {
+		fmt.Fprintf(b, "line %d\n", i)
+	}
+	lines := b.recent()
+	require.Len(t, lines, maxMessages)
+	require.Equal(t, fmt.Sprintf("line %d", maxMessages-1), lines[len(lines)-1])
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"errors"
+
+	"go.temporal.io/api/serviceerror"
+	"go.temporal.io/sdk/temporal"
+)
+
+// classifyError returns a short category for an error starting or waiting for
+// a workflow, for counting errors by kind.
+func classifyError(err error) string {
+	var (
+		resourceExhausted  *serviceerror.ResourceExhausted
+		unavailable        *serviceerror.Unavailable
+		deadlineExceeded   *serviceerror.DeadlineExceeded
+		namespaceNotActive *serviceerror.NamespaceNotActive
+		namespaceNotFound  *serviceerror.NamespaceNotFound
+		invalidArgument    *serviceerror.InvalidArgument
+		permissionDenied   *serviceerror.PermissionDenied
+		workflowErr        *temporal.WorkflowExecutionError
+	)
+
+	switch {
+	case errors.As(err, &workflowErr):
+		// How the workflow closed is the direct cause. Errors further down,
+		// such as an activity's timeout or an RPC error it returned, are why
+		// it failed.
+		if kind := classifyClose(errors.Unwrap(workflowErr)); kind != "" {
+			return kind
+		}
+		return "WorkflowFailed"
+	case errors.As(err, &resourceExhausted):
+		return "ResourceExhausted"
+	case errors.As(err, &unavailable):
+		return "Unavailable"
+	case errors.As(err, &deadlineExceeded), errors.Is(err, context.DeadlineExceeded):
+		return "DeadlineExceeded"
+	case errors.As(err, &namespaceNotActive):
+		return "NamespaceNotActive"
+	case errors.As(err, &namespaceNotFound):
+		return "NamespaceNotFound"
+	case errors.As(err, &invalidArgument):
+		return "InvalidArgument"
+	case errors.As(err, &permissionDenied):
+		return "PermissionDenied"
+	default:
+		if kind := classifyClose(err); kind != "" {
+			return kind
+		}
+		return "Other"
+	}
+}
+
+// classifyClose returns the category for a workflow that timed out, was
+// canceled or was terminated, or "" for any other error. Only err itself is
+// checked, not the errors it wraps.
+func classifyClose(err error) string {
+	switch err.(type) {
+	case *temporal.TimeoutError:
+		return "WorkflowTimedOut"
+	case *temporal.CanceledError:
+		return "WorkflowCanceled"
+	case *temporal.TerminatedError:
+		return "WorkflowTerminated"
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/api/serviceerror"
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/testsuite"
+	"go.temporal.io/sdk/workflow"
+)
+
+func TestClassifyError(t *testing.T) {
+	require.Equal(t, "ResourceExhausted", classifyError(serviceerror.NewResourceExhausted(0, "busy")))
+	require.Equal(t, "NamespaceNotActive", classifyError(fmt.Errorf("start: %w", serviceerror.NewNamespaceNotActive("ns", "a", "b"))))
+	require.Equal(t, "DeadlineExceeded", classifyError(context.DeadlineExceeded))
+	require.Equal(t, "Other", classifyError(errors.New("boom")))
+}
+
+func TestClassifyWorkflowError(t *testing.T) {
+	// workflowError runs fn as a workflow and returns the error a client waiting
+	// on it would see.
+	workflowError := func(fn func(ctx workflow.Context) error) error {
+		var suite testsuite.WorkflowTestSuite
+		env := suite.NewTestWorkflowEnvironment()
+		env.RegisterActivityWithOptions(func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		}, activity.RegisterOptions{Name: "Block"})
+		env.ExecuteWorkflow(fn)
+		return env.GetWorkflowError()
+	}
+
+	// An activity timing out fails the workflow; the workflow didn't time out.
+	err := workflowError(func(ctx workflow.Context) error {
+		ctx = workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
+			StartToCloseTimeout: time.Second,
+			RetryPolicy:         &temporal.RetryPolicy{MaximumAttempts: 1},
+		})
+		return workflow.ExecuteActivity(ctx, "Block").Get(ctx, nil)
+	})
+	var timeoutErr *temporal.TimeoutError
+	require.ErrorAs(t, err, &timeoutErr)
+	require.Equal(t, "WorkflowFailed", classifyError(err))
+
+	err = workflowError(func(ctx workflow.Context) error {
+		return temporal.NewApplicationError("boom", "Boom")
+	})
+	require.Equal(t, "WorkflowFailed", classifyError(err))
+
+	err = workflowError(func(ctx workflow.Context) error {
+		return temporal.NewCanceledError()
+	})
+	require.Equal(t, "WorkflowCanceled", classifyError(err))
+}
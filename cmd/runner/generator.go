@@ -0,0 +1,500 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pborman/uuid"
+	"github.com/uber-go/tally/v4"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.temporal.io/sdk/client"
+)
+
+// messages receives the runner's per-execution error output. The dashboard
+// replaces it so that errors don't garble the screen.
+var messages io.Writer = os.Stderr
+
+// tracer wraps each execution in a span, parenting the SDK's spans for the
+// start, workflow tasks and activities. It is a no-op unless OpenTelemetry is
+// configured.
+var tracer = otel.Tracer("github.com/temporalio/benchmark-workers/cmd/runner")
+
+// workload describes the workflow executions the runner starts.
+type workload struct {
+	WorkflowType string
+	Scenario     string
+	SignalType   string
+	Input        []interface{}
+	Wait         bool
+	// HistorySample is the fraction of completed executions whose history is
+	// fetched and measured.
+	HistorySample float64
+	// ReadOps are the read operations cycled through by targets with a read
+	// rate, with up to ReadConcurrency reads in flight per target.
+	ReadOps         []string
+	ReadConcurrency int
+	// TaskQueueInterval is how often each target's task queue is described,
+	// with zero meaning never.
+	TaskQueueInterval time.Duration
+	// Ramps change the routing of worker deployments during the run.
+	Ramps []rampStep
+}
+
+// backoffOptions controls how the runner slows down when starts fail.
+type backoffOptions struct {
+	Disabled    bool
+	MaxInterval int
+	Factor      int
+}
+
+// loadGenerator drives a single target, keeping up to Concurrency executions
+// in flight and, if a Rate is set, pacing starts to that rate. Concurrency and
+// rate can be changed, and the generator paused, while it runs.
+type loadGenerator struct {
+	target   target
+	clients  *clusterClient
+	workload workload
+	backoff  backoffOptions
+	avail    *availabilityTracker
+	latency  *latencyHistogram
+	// corrected is measured from when each execution was due to start, so
+	// that stalls in the runner don't hide latency: its scheduled start with a
+	// target rate, or when it asked for a slot without one.
+	corrected *latencyHistogram
+	history   history
+	histories *historySampler
+	reads     *readGenerator
+	queues    *taskQueueMonitor
+	metrics   *generatorMetrics
+
+	slots *slots
+	wg    sync.WaitGroup
+
+	scheduleMu sync.Mutex
+	rate       float64
+	nextStart  time.Time
+
+	resultMu   sync.Mutex
+	lastResult error
+	hasResult  bool
+
+	errorMu     sync.Mutex
+	errorCounts map[string]uint64
+
+	started     atomic.Uint64
+	startFailed atomic.Uint64
+	completed   atomic.Uint64
+	failed      atomic.Uint64
+}
+
+func newLoadGenerator(t target, cc *clusterClient, w workload, b backoffOptions, scope tally.Scope) *loadGenerator {
+	g := &loadGenerator{
+		target:    t,
+		clients:   cc,
+		workload:  w,
+		backoff:   b,
+		avail:     newAvailabilityTracker(),
+		latency:   newLatencyHistogram(),
+		corrected: newLatencyHistogram(),
+		histories: newHistorySampler(w.HistorySample),
+		metrics:   newGeneratorMetrics(scope, t, w),
+		slots:     newSlots(t.Concurrency),
+
+		errorCounts: make(map[string]uint64),
+	}
+	if t.ReadRate > 0 {
+		g.reads = newReadGenerator(t, cc, w.ReadOps, w.ReadConcurrency, g.metrics)
+	}
+	if w.TaskQueueInterval > 0 {
+		g.queues = newTaskQueueMonitor(t, cc, g.metrics)
+	}
+	g.setRate(t.Rate)
+	return g
+}
+
+// setConcurrency changes the maximum number of executions in flight.
+func (g *loadGenerator) setConcurrency(n int) {
+	g.slots.setLimit(n)
+}
+
+// setRate changes the target starts per second, with zero meaning no limit.
+func (g *loadGenerator) setRate(r float64) {
+	g.scheduleMu.Lock()
+	defer g.scheduleMu.Unlock()
+
+	g.rate = max(r, 0)
+	g.nextStart = time.Time{}
+}
+
+func (g *loadGenerator) setPaused(paused bool) {
+	g.slots.setPaused(paused)
+	g.resetSchedule()
+}
+
+// resetSchedule restarts the start rate schedule from the next start.
+func (g *loadGenerator) resetSchedule() {
+	g.scheduleMu.Lock()
+	defer g.scheduleMu.Unlock()
+
+	g.nextStart = time.Time{}
+}
+
+// schedule returns when the next execution is due, or false without a target
+// rate. Starts are due at fixed intervals, and a generator that falls behind,
+// for example because its concurrency is used up or it backed off after
+// errors, starts the executions it missed as soon as it can to catch up.
+func (g *loadGenerator) schedule() (time.Time, bool) {
+	g.scheduleMu.Lock()
+	defer g.scheduleMu.Unlock()
+
+	if g.rate == 0 {
+		return time.Time{}, false
+	}
+	if g.nextStart.IsZero() {
+		g.nextStart = time.Now()
+	}
+	due := g.nextStart
+	g.nextStart = g.nextStart.Add(time.Duration(float64(time.Second) / g.rate))
+	return due, true
+}
+
+// currentRate returns the target starts per second, with zero meaning no
+// limit.
+func (g *loadGenerator) currentRate() float64 {
+	g.scheduleMu.Lock()
+	defer g.scheduleMu.Unlock()
+
+	return g.rate
+}
+
+func (g *loadGenerator) finished() uint64 {
+	return g.completed.Load() + g.failed.Load() + g.startFailed.Load()
+}
+
+func (g *loadGenerator) inFlight() int {
+	_, used, _ := g.slots.state()
+	return used
+}
+
+func (g *loadGenerator) sample() counterSample {
+	return counterSample{
+		Time:        time.Now(),
+		Started:     g.started.Load(),
+		StartFailed: g.startFailed.Load(),
+		Completed:   g.completed.Load(),
+		Failed:      g.failed.Load(),
+		InFlight:    g.inFlight(),
+	}
+}
+
+func (g *loadGenerator) recordError(err error) {
+	g.errorMu.Lock()
+	defer g.errorMu.Unlock()
+
+	g.errorCounts[classifyError(err)]++
+}
+
+// errorClasses returns the number of errors seen of each class.
+func (g *loadGenerator) errorClasses() map[string]uint64 {
+	g.errorMu.Lock()
+	defer g.errorMu.Unlock()
+
+	counts := make(map[string]uint64, len(g.errorCounts))
+	for class, n := range g.errorCounts {
+		counts[class] = n
+	}
+	return counts
+}
+
+func (g *loadGenerator) setResult(err error) {
+	g.resultMu.Lock()
+	defer g.resultMu.Unlock()
+
+	g.lastResult = err
+	g.hasResult = true
+}
+
+// takeResult returns the most recent execution result since the last call.
+func (g *loadGenerator) takeResult() (err error, updated bool) {
+	g.resultMu.Lock()
+	defer g.resultMu.Unlock()
+
+	err, updated = g.lastResult, g.hasResult
+	g.lastResult, g.hasResult = nil, false
+	return err, updated
+}
+
+func (g *loadGenerator) start(ctx context.Context) (client.WorkflowRun, error) {
+	c := g.clients.Client()
+
+	if g.workload.SignalType != "" {
+		wID := uuid.New()
+		return c.SignalWithStartWorkflow(
+			ctx,
+			wID,
+			g.workload.SignalType,
+			nil,
+			client.StartWorkflowOptions{
+				ID:        wID,
+				TaskQueue: g.target.TaskQueue,
+			},
+			g.workload.WorkflowType,
+			g.workload.Input...,
+		)
+	}
+
+	return c.ExecuteWorkflow(
+		ctx,
+		client.StartWorkflowOptions{
+			TaskQueue: g.target.TaskQueue,
+		},
+		g.workload.WorkflowType,
+		g.workload.Input...,
+	)
+}
+
+// execute runs one execution, which was due to start at due.
+func (g *loadGenerator) execute(due time.Time) error {
+	startTime := time.Now()
+
+	ctx, span := tracer.Start(context.Background(), "BenchmarkExecution:"+g.workload.WorkflowType)
+	defer span.End()
+	span.SetAttributes(attribute.String("benchmark.target", g.target.Label))
+
+	g.metrics.startsAttempted.Inc(1)
+	wf, err := g.start(ctx)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		fmt.Fprintf(messages, "[%s] Unable to start workflow: %v\n", g.target.Label, err)
+		g.startFailed.Add(1)
+		g.recordError(err)
+		g.metrics.startFailed(err)
+		g.avail.startFailed(time.Now())
+		g.clients.handleError(err)
+		return err
+	}
+	g.started.Add(1)
+	g.metrics.startsSucceeded.Inc(1)
+	g.avail.startSucceeded(time.Now())
+
+	e := execution{WorkflowID: wf.GetID(), RunID: wf.GetRunID()}
+	if g.reads != nil {
+		g.reads.started(e, g.workload.Wait)
+		defer g.reads.closed(e)
+	}
+
+	if g.workload.Wait {
+		err = g.result(ctx, wf)
+		if err != nil {
+			span.SetStatus(codes.Error, err.Error())
+			fmt.Fprintf(messages, "[%s] Workflow failed: %v\n", g.target.Label, err)
+			g.failed.Add(1)
+			g.recordError(err)
+			g.metrics.executionFailed(err)
+			return err
+		}
+	}
+
+	latency := time.Since(startTime)
+
+	g.completed.Add(1)
+	g.avail.completed(time.Now(), latency)
+	g.latency.record(latency)
+	g.metrics.completed.Inc(1)
+	g.metrics.latency.RecordDuration(latency)
+	corrected := time.Since(due)
+	g.corrected.record(corrected)
+	g.metrics.correctedLatency.RecordDuration(corrected)
+
+	if g.workload.Wait && g.histories.sample() {
+		g.wg.Add(1)
+		go func() {
+			defer g.wg.Done()
+
+			if err := g.histories.record(context.Background(), g.clients.Client(), e.WorkflowID, e.RunID); err != nil {
+				fmt.Fprintf(messages, "[%s] Unable to fetch workflow history: %v\n", g.target.Label, err)
+			}
+		}()
+	}
+	return nil
+}
+
+// result waits for an execution to close. If the namespace fails over in the
+// meantime, whether the wait fails with NamespaceNotActive or is still going,
+// the result is fetched again from the newly active cluster.
+func (g *loadGenerator) result(ctx context.Context, wf client.WorkflowRun) error {
+	for {
+		changed := g.clients.activeChanged()
+		getCtx, cancel := context.WithCancel(ctx)
+		go func() {
+			select {
+			case <-changed:
+				cancel()
+			case <-getCtx.Done():
+			}
+		}()
+		err := wf.Get(getCtx, nil)
+		cancel()
+		if err == nil {
+			return nil
+		}
+
+		g.clients.handleError(err)
+		select {
+		case <-changed:
+		default:
+			return err
+		}
+		if ctx.Err() != nil {
+			return err
+		}
+		wf = g.clients.Client().GetWorkflow(ctx, wf.GetID(), wf.GetRunID())
+	}
+}
+
+// run starts executions until ctx is done and then waits for those in flight
+// to finish.
+func (g *loadGenerator) run(ctx context.Context) {
+	defer g.wg.Wait()
+
+	go g.record(ctx)
+	if g.queues != nil {
+		go g.queues.run(ctx, g.workload.TaskQueueInterval)
+	}
+	if g.reads != nil {
+		g.wg.Add(1)
+		go func() {
+			defer g.wg.Done()
+			g.reads.run(ctx)
+		}()
+	}
+
+	g.resetSchedule()
+
+	currentInterval := 1
+
+	for ctx.Err() == nil {
+		// Without a rate the runner is closed-loop, and an execution is due
+		// when it asks for a slot, so time spent waiting for one counts
+		// towards its corrected latency.
+		requested := time.Now()
+		if err := g.slots.acquire(ctx); err != nil {
+			return
+		}
+		due, scheduled := g.schedule()
+		if !scheduled {
+			due = requested
+		}
+		if wait := time.Until(due); wait > 0 {
+			select {
+			case <-time.After(wait):
+			case <-ctx.Done():
+				g.slots.release()
+				return
+			}
+		}
+
+		g.wg.Add(1)
+		go func() {
+			defer g.wg.Done()
+			defer g.slots.release()
+
+			g.setResult(g.execute(due))
+		}()
+
+		lastErr, updated := g.takeResult()
+
+		if g.backoff.Disabled || !updated {
+			continue
+		}
+
+		if lastErr != nil {
+			fmt.Fprintf(messages, "[%s] Waiting for %d seconds before retrying to start workflow...\n", g.target.Label, currentInterval)
+			g.metrics.backoffSleeps.Inc(1)
+			select {
+			case <-time.After(time.Duration(currentInterval) * time.Second):
+			case <-ctx.Done():
+			}
+			nInterval := currentInterval * g.backoff.Factor
+			if nInterval < g.backoff.MaxInterval && g.backoff.MaxInterval != 0 {
+				currentInterval *= g.backoff.Factor
+			}
+		} else {
+			currentInterval = 1
+		}
+	}
+}
+
+// record samples the generator's counters, and updates its gauges, every
+// second until ctx is done.
+func (g *loadGenerator) record(ctx context.Context) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		g.history.add(g.sample())
+
+		limit, used, _ := g.slots.state()
+		g.metrics.inFlight.Update(float64(used))
+		g.metrics.targetConcurrency.Update(float64(limit))
+		g.metrics.targetRate.Update(g.currentRate())
+
+		// Measure each failover's baseline before its latency is pruned.
+		for _, f := range g.clients.Failovers() {
+			g.avail.failedOver(f)
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (g *loadGenerator) results(elapsed time.Duration) targetResults {
+	var failovers []failoverImpact
+	for _, f := range g.clients.Failovers() {
+		failovers = append(failovers, g.avail.impact(f))
+	}
+
+	var reads map[string]readSummary
+	if g.reads != nil {
+		reads = g.reads.summary(elapsed)
+	}
+	var queues map[string]taskQueueSummary
+	if g.queues != nil {
+		queues = g.queues.summary()
+	}
+
+	completed := g.completed.Load()
+	return targetResults{
+		Label:               g.target.Label,
+		WorkflowType:        g.workload.WorkflowType,
+		Namespace:           g.target.Namespace,
+		TaskQueue:           g.target.TaskQueue,
+		ConcurrentWorkflows: g.target.Concurrency,
+		TargetRate:          g.target.Rate,
+		TargetReadRate:      g.target.ReadRate,
+		Started:             g.started.Load(),
+		StartFailed:         g.startFailed.Load(),
+		Completed:           completed,
+		Failed:              g.failed.Load(),
+		Rate:                float64(completed) / elapsed.Seconds(),
+		Latency:             g.latency.summary(),
+		CorrectedLatency:    g.corrected.summary(),
+		History:             g.histories.summary(),
+		Reads:               reads,
+		TaskQueueStats:      queues,
+		Errors:              g.errorClasses(),
+		Outages:             g.avail.outageWindows(time.Now()),
+		Failovers:           failovers,
+	}
+}
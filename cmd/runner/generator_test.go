@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/uber-go/tally/v4"
+	"go.temporal.io/sdk/client"
+)
+
+func TestSchedule(t *testing.T) {
+	g := newLoadGenerator(target{Concurrency: 1, Rate: 10}, nil, workload{}, backoffOptions{}, tally.NoopScope)
+
+	first, ok := g.schedule()
+	require.True(t, ok)
+
+	// Starts stay on the 100ms schedule when the runner falls behind it, so
+	// that it catches up.
+	time.Sleep(350 * time.Millisecond)
+	for i := 1; i <= 5; i++ {
+		due, ok := g.schedule()
+		require.True(t, ok)
+		require.Equal(t, first.Add(time.Duration(i)*100*time.Millisecond), due)
+	}
+
+	// Changing the rate restarts the schedule.
+	g.setRate(20)
+	due, _ := g.schedule()
+	require.WithinDuration(t, time.Now(), due, 10*time.Millisecond)
+
+	// Without a rate nothing is scheduled.
+	g.setRate(0)
+	_, ok = g.schedule()
+	require.False(t, ok)
+}
+
+// stallingClient starts workflows instantly, except for the stall'th start,
+// which takes stallFor.
+type stallingClient struct {
+	client.Client
+	starts   atomic.Int64
+	stall    int64
+	stallFor time.Duration
+}
+
+func (c *stallingClient) ExecuteWorkflow(context.Context, client.StartWorkflowOptions, interface{}, ...interface{}) (client.WorkflowRun, error) {
+	if c.starts.Add(1) == c.stall {
+		time.Sleep(c.stallFor)
+	}
+	return &fakeRun{id: "wf", runID: "run"}, nil
+}
+
+func TestCorrectedLatencyRecovers(t *testing.T) {
+	c := &stallingClient{stall: 5, stallFor: 500 * time.Millisecond}
+	clients := newClusterClient("default", []*cluster{{Name: "a", client: c}})
+	g := newLoadGenerator(target{Concurrency: 1, Rate: 50}, clients, workload{WorkflowType: "Echo"}, backoffOptions{Disabled: true}, tally.NoopScope)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	g.run(ctx)
+
+	// The starts missed during the stall are made up, and those after it are
+	// on time again rather than all 500ms late.
+	require.Greater(t, g.completed.Load(), uint64(90))
+	require.Less(t, time.Duration(g.corrected.h.ValueAtQuantile(50))*time.Microsecond, 100*time.Millisecond)
+	require.GreaterOrEqual(t, time.Duration(g.corrected.h.Max())*time.Microsecond, 400*time.Millisecond)
+}
+
+func TestCorrectedLatencyClosedLoop(t *testing.T) {
+	c := &stallingClient{stall: 5, stallFor: 500 * time.Millisecond}
+	clients := newClusterClient("default", []*cluster{{Name: "a", client: c}})
+	g := newLoadGenerator(target{Concurrency: 1}, clients, workload{WorkflowType: "Echo"}, backoffOptions{Disabled: true}, tally.NoopScope)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	g.run(ctx)
+
+	// Only the stalled start is slow, but the start that waited for its slot
+	// was due when it asked for it, so it is slow too once corrected.
+	slow := func(h *latencyHistogram) (n int64) {
+		for _, bar := range h.h.Distribution() {
+			if time.Duration(bar.From)*time.Microsecond >= 400*time.Millisecond {
+				n += bar.Count
+			}
+		}
+		return n
+	}
+	require.Equal(t, g.latency.h.TotalCount(), g.corrected.h.TotalCount())
+	require.Equal(t, int64(1), slow(g.latency))
+	require.Equal(t, int64(2), slow(g.corrected))
+}
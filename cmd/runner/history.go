@@ -0,0 +1,70 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// historySize is how many one second samples of each target are kept.
+const historySize = 300
+
+// counterSample is a point in time snapshot of a target's counters.
+type counterSample struct {
+	Time        time.Time
+	Started     uint64
+	StartFailed uint64
+	Completed   uint64
+	Failed      uint64
+	InFlight    int
+}
+
+// history keeps the most recent counter samples of a target, so that rates
+// can be computed over a recent window rather than the whole run.
+type history struct {
+	mu      sync.Mutex
+	samples []counterSample
+}
+
+func (h *history) add(s counterSample) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.samples = append(h.samples, s)
+	if len(h.samples) > historySize {
+		h.samples = h.samples[len(h.samples)-historySize:]
+	}
+}
+
+// series returns the samples held, oldest first.
+func (h *history) series() []counterSample {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return append([]counterSample(nil), h.samples...)
+}
+
+// rates returns starts and completions per second over roughly the last
+// window.
+func (h *history) rates(window time.Duration) (startRate, completionRate float64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if len(h.samples) < 2 {
+		return 0, 0
+	}
+
+	last := h.samples[len(h.samples)-1]
+	first := h.samples[0]
+	for i := len(h.samples) - 2; i >= 0; i-- {
+		first = h.samples[i]
+		if last.Time.Sub(first.Time) >= window {
+			break
+		}
+	}
+
+	elapsed := last.Time.Sub(first.Time).Seconds()
+	if elapsed <= 0 {
+		return 0, 0
+	}
+	return float64(last.Started-first.Started) / elapsed, float64(last.Completed-first.Completed) / elapsed
+}
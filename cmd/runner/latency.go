@@ -0,0 +1,84 @@
+package main
+
+import (
+	"sync"
+	"time"
+
+	"github.com/HdrHistogram/hdrhistogram-go"
+)
+
+const (
+	// Latencies are recorded in microseconds, from 1µs to a day, to three
+	// significant figures.
+	minLatency     = int64(1)
+	maxLatency     = int64(24 * time.Hour / time.Microsecond)
+	latencySigFigs = 3
+)
+
+// latencySummary is the distribution of a latency histogram.
+type latencySummary struct {
+	Count int64  `json:"count"`
+	Mean  string `json:"mean"`
+	P50   string `json:"p50"`
+	P90   string `json:"p90"`
+	P95   string `json:"p95"`
+	P99   string `json:"p99"`
+	P999  string `json:"p999"`
+	Max   string `json:"max"`
+}
+
+// latencyHistogram is a histogram of latencies that is safe for concurrent
+// use and can be merged with histograms from other runners.
+type latencyHistogram struct {
+	mu sync.Mutex
+	h  *hdrhistogram.Histogram
+}
+
+func newLatencyHistogram() *latencyHistogram {
+	return &latencyHistogram{h: hdrhistogram.New(minLatency, maxLatency, latencySigFigs)}
+}
+
+func (l *latencyHistogram) record(d time.Duration) {
+	v := min(max(d.Microseconds(), minLatency), maxLatency)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.h.RecordValue(v)
+}
+
+// snapshot returns a serializable copy of the histogram.
+func (l *latencyHistogram) snapshot() *hdrhistogram.Snapshot {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.h.Export()
+}
+
+// merge adds the values from a snapshot to the histogram.
+func (l *latencyHistogram) merge(s *hdrhistogram.Snapshot) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.h.Merge(hdrhistogram.Import(s))
+}
+
+func (l *latencyHistogram) summary() latencySummary {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	us := func(v int64) string {
+		return (time.Duration(v) * time.Microsecond).String()
+	}
+
+	return latencySummary{
+		Count: l.h.TotalCount(),
+		Mean:  us(int64(l.h.Mean())),
+		P50:   us(l.h.ValueAtQuantile(50)),
+		P90:   us(l.h.ValueAtQuantile(90)),
+		P95:   us(l.h.ValueAtQuantile(95)),
+		P99:   us(l.h.ValueAtQuantile(99)),
+		P999:  us(l.h.ValueAtQuantile(99.9)),
+		Max:   us(l.h.Max()),
+	}
+}
@@ -9,30 +9,72 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"os/signal"
+	"slices"
+	"sort"
 	"strconv"
+	"strings"
+	"sync"
+	"syscall"
 	"time"
 
-	"github.com/alitto/pond"
-	"github.com/pborman/uuid"
-	"github.com/uber-go/tally/v4/prometheus"
+	"github.com/HdrHistogram/hdrhistogram-go"
+	prom "github.com/prometheus/client_golang/prometheus"
+	"github.com/temporalio/benchmark-workers/activities"
+	"github.com/temporalio/benchmark-workers/internal/metrics"
+	"github.com/temporalio/benchmark-workers/internal/profile"
+	"github.com/temporalio/benchmark-workers/internal/telemetry"
+	"github.com/temporalio/benchmark-workers/workflows"
+	"github.com/uber-go/tally/v4"
+	"go.temporal.io/sdk/contrib/opentelemetry"
 	sdktally "go.temporal.io/sdk/contrib/tally"
 	"go.uber.org/automaxprocs/maxprocs"
 
 	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/worker"
 )
 
 var (
-	nWorkflows  = flag.Int("c", 10, "concurrent workflows")
-	sWorkflow = flag.String("t", "", "workflow type")
-	sSignalType = flag.String("s", "", "signal type")
-	bWait = flag.Bool("w", true, "wait for workflows to complete")
-	sNamespace = flag.String("n", "default", "namespace")
-	sTaskQueue = flag.String("tq", "benchmark", "task queue")
-	nMaxInterval = flag.Int("max-interval", 60, "maximum interval (in seconds) for exponential backoff")
-	nFactor = flag.Int("backoff-factor", 2, "factor for exponential backoff")
-	bDisableBackoff = flag.Bool("disable-backoff", false, "disable exponential backoff on errors")
+	nWorkflows       = flag.Int("c", 10, "concurrent workflows")
+	sWorkflow        = flag.String("t", "", "workflow type")
+	sScenario        = flag.String("scenario", "", "scenario name to label the runner's metrics with")
+	sSignalType      = flag.String("s", "", "signal type")
+	bWait            = flag.Bool("w", true, "wait for workflows to complete")
+	sNamespace       = flag.String("n", "default", "namespace")
+	sTaskQueue       = flag.String("tq", "benchmark", "task queue")
+	nMaxInterval     = flag.Int("max-interval", 60, "maximum interval (in seconds) for exponential backoff")
+	nFactor          = flag.Int("backoff-factor", 2, "factor for exponential backoff")
+	bDisableBackoff  = flag.Bool("disable-backoff", false, "disable exponential backoff on errors")
+	dFailoverCheck   = flag.Duration("failover-check-interval", 2*time.Second, "how often to check which cluster a namespace is active in when several endpoints are given")
+	bEmbeddedWorker  = flag.Bool("embedded-worker", false, "run the benchmark worker in-process on the same task queue")
+	dDuration        = flag.Duration("d", 0, "how long to run for (0 = run until interrupted)")
+	sResultsFile     = flag.String("o", "", "file to write a JSON summary of the run to on exit")
+	fRate            = flag.Float64("r", 0, "target workflow starts per second (0 = start a new workflow as each completes)")
+	sCoordinator     = flag.String("coordinator", "", "run as coordinator for -agents runner agents, listening on this address")
+	nAgents          = flag.Int("agents", 1, "number of agents the coordinator waits for")
+	bShardTargets    = flag.Bool("shard-targets", false, "deal whole targets out to agents instead of splitting each target's concurrency and rate")
+	bDashboard       = flag.Bool("tui", false, "show a live terminal dashboard instead of periodic status lines")
+	sControl         = flag.String("control", "", "address to serve the HTTP status and control API on")
+	sAgent           = flag.String("agent", "", "run as an agent, taking the workload from the coordinator at this URL")
+	fHistorySample   = flag.Float64("history-sample", 0, "fraction of completed workflows whose history is fetched and measured")
+	fReadRate        = flag.Float64("read-rate", 0, "target history and describe reads per second against started workflows (0 = no reads)")
+	nReadConcurrent  = flag.Int("read-concurrency", 10, "maximum reads in flight per target")
+	sReadOps         = flag.String("read-ops", strings.Join(readOps, ","), "comma separated read operations to cycle through")
+	dTaskQueueStats  = flag.Duration("task-queue-stats-interval", 0, "how often to describe each target's task queue for pollers, backlog and rates, e.g. 10s (0 = never)")
+	sDeploymentName  = flag.String("deployment-name", "", "worker deployment that -ramp steps apply to, unless they name one")
+	sPprof           = flag.String("pprof", "", "address to serve the net/http/pprof endpoints on")
+	sProfileDir      = flag.String("profile-dir", "", "directory to write CPU, heap, mutex and block profiles and a runtime trace to")
+	dProfileAfter    = flag.Duration("profile-after", 0, "time after startup to start writing profiles to -profile-dir")
+	dProfileDuration = flag.Duration("profile-duration", 30*time.Second, "time to profile for when writing profiles to -profile-dir")
+	targetSpecs      targetList
+	rampSpecs        rampList
 )
 
+func init() {
+	flag.Var(&targetSpecs, "target", "`n=namespace,tq=task-queue,c=concurrency,r=rate,rr=read-rate,label=name` to drive concurrently, instead of the -n and -tq target (repeatable)")
+	flag.Var(&rampSpecs, "ramp", "`at=duration,build-id=id,percentage=p` worker deployment version to ramp to, or without a percentage make current, at a time into the run (repeatable)")
+}
+
 // Track which flags were explicitly set
 var flagsSet = make(map[string]bool)
 
@@ -71,6 +113,30 @@ func getBoolValue(flagName, envName string, flagValue, defaultValue bool) bool {
 	return defaultValue
 }
 
+func getFloatValue(flagName, envName string, flagValue, defaultValue float64) float64 {
+	if flagsSet[flagName] {
+		return flagValue
+	}
+	if envValue := os.Getenv(envName); envValue != "" {
+		if parsed, err := strconv.ParseFloat(envValue, 64); err == nil {
+			return parsed
+		}
+	}
+	return defaultValue
+}
+
+func getDurationValue(flagName, envName string, flagValue, defaultValue time.Duration) time.Duration {
+	if flagsSet[flagName] {
+		return flagValue
+	}
+	if envValue := os.Getenv(envName); envValue != "" {
+		if parsed, err := time.ParseDuration(envValue); err == nil {
+			return parsed
+		}
+	}
+	return defaultValue
+}
+
 func main() {
 	flag.Usage = func() {
 		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] [workflow input] ...\n", os.Args[0])
@@ -78,10 +144,35 @@ func main() {
 		fmt.Fprintf(flag.CommandLine.Output(), "\nEnvironment variables (used if flag not set):\n")
 		fmt.Fprintf(flag.CommandLine.Output(), "  TEMPORAL_CONCURRENT_WORKFLOWS\n")
 		fmt.Fprintf(flag.CommandLine.Output(), "  TEMPORAL_WORKFLOW_TYPE\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "  TEMPORAL_SCENARIO\n")
 		fmt.Fprintf(flag.CommandLine.Output(), "  TEMPORAL_SIGNAL_TYPE\n")
 		fmt.Fprintf(flag.CommandLine.Output(), "  TEMPORAL_WAIT\n")
 		fmt.Fprintf(flag.CommandLine.Output(), "  TEMPORAL_NAMESPACE\n")
 		fmt.Fprintf(flag.CommandLine.Output(), "  TEMPORAL_TASK_QUEUE\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "  TEMPORAL_EMBEDDED_WORKER\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "  TEMPORAL_DURATION\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "  TEMPORAL_RESULTS_FILE\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "  TEMPORAL_RATE\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "  TEMPORAL_TARGETS (semicolon separated list of -target values)\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "  TEMPORAL_GRPC_ENDPOINT (comma separated to follow failovers between clusters)\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "  TEMPORAL_FAILOVER_CHECK_INTERVAL\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "  TEMPORAL_COORDINATOR_LISTEN\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "  TEMPORAL_AGENTS\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "  TEMPORAL_SHARD_TARGETS\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "  TEMPORAL_COORDINATOR_URL\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "  CONTROL_ENDPOINT\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "  TEMPORAL_TUI\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "  TEMPORAL_HISTORY_SAMPLE\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "  TEMPORAL_READ_RATE\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "  TEMPORAL_READ_CONCURRENCY\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "  TEMPORAL_READ_OPS\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "  TEMPORAL_TASK_QUEUE_STATS_INTERVAL\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "  TEMPORAL_DEPLOYMENT_NAME\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "  TEMPORAL_RAMPS (semicolon separated list of -ramp values)\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "  PPROF_ENDPOINT\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "  PROFILE_DIR\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "  PROFILE_AFTER\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "  PROFILE_DURATION\n")
 	}
 
 	flag.Parse()
@@ -98,6 +189,7 @@ func main() {
 	// Apply precedence: command line > environment variable > default
 	concurrentWorkflows := getIntValue("c", "TEMPORAL_CONCURRENT_WORKFLOWS", *nWorkflows, 10)
 	workflowType := getStringValue("t", "TEMPORAL_WORKFLOW_TYPE", *sWorkflow, "")
+	scenario := getStringValue("scenario", "TEMPORAL_SCENARIO", *sScenario, "")
 	signalType := getStringValue("s", "TEMPORAL_SIGNAL_TYPE", *sSignalType, "")
 	waitForCompletion := getBoolValue("w", "TEMPORAL_WAIT", *bWait, true)
 	namespace := getStringValue("n", "TEMPORAL_NAMESPACE", *sNamespace, "default")
@@ -105,11 +197,133 @@ func main() {
 	disableBackOff := getBoolValue("disable-backoff", "TEMPORAL_DISABLE_ERROR_BACKOFF", *bDisableBackoff, false)
 	maxInterval := getIntValue("max-interval", "TEMPORAL_BACKOFF_MAX_INTERVAL", *nMaxInterval, 60)
 	factor := getIntValue("backoff-factor", "TEMPORAL_BACKOFF_FACTOR", *nFactor, 2)
+	embeddedWorker := getBoolValue("embedded-worker", "TEMPORAL_EMBEDDED_WORKER", *bEmbeddedWorker, false)
+	duration := getDurationValue("d", "TEMPORAL_DURATION", *dDuration, 0)
+	resultsFile := getStringValue("o", "TEMPORAL_RESULTS_FILE", *sResultsFile, "")
+	startRate := getFloatValue("r", "TEMPORAL_RATE", *fRate, 0)
+	failoverCheckInterval := getDurationValue("failover-check-interval", "TEMPORAL_FAILOVER_CHECK_INTERVAL", *dFailoverCheck, 2*time.Second)
+	coordinatorAddr := getStringValue("coordinator", "TEMPORAL_COORDINATOR_LISTEN", *sCoordinator, "")
+	agents := getIntValue("agents", "TEMPORAL_AGENTS", *nAgents, 1)
+	shardTargets := getBoolValue("shard-targets", "TEMPORAL_SHARD_TARGETS", *bShardTargets, false)
+	coordinatorURL := getStringValue("agent", "TEMPORAL_COORDINATOR_URL", *sAgent, "")
+	controlAddr := getStringValue("control", "CONTROL_ENDPOINT", *sControl, "")
+	showDashboard := getBoolValue("tui", "TEMPORAL_TUI", *bDashboard, false)
+	historySample := getFloatValue("history-sample", "TEMPORAL_HISTORY_SAMPLE", *fHistorySample, 0)
+	taskQueueInterval := getDurationValue("task-queue-stats-interval", "TEMPORAL_TASK_QUEUE_STATS_INTERVAL", *dTaskQueueStats, 0)
+
+	readRate := getFloatValue("read-rate", "TEMPORAL_READ_RATE", *fReadRate, 0)
+	readConcurrency := getIntValue("read-concurrency", "TEMPORAL_READ_CONCURRENCY", *nReadConcurrent, 10)
+
+	if historySample < 0 || historySample > 1 {
+		log.Fatalf("History sample fraction must be between 0 and 1")
+	}
+	if taskQueueInterval < 0 {
+		log.Fatalf("Task queue stats interval must not be negative")
+	}
+	if concurrentWorkflows < 1 {
+		log.Fatalf("Concurrency must be at least 1")
+	}
+	if readConcurrency < 1 {
+		log.Fatalf("Read concurrency must be at least 1")
+	}
+	ops, err := parseReadOps(getStringValue("read-ops", "TEMPORAL_READ_OPS", *sReadOps, strings.Join(readOps, ",")))
+	if err != nil {
+		log.Fatalf("Invalid read operations: %v", err)
+	}
+
+	specs := []string(targetSpecs)
+	if len(specs) == 0 && os.Getenv("TEMPORAL_TARGETS") != "" {
+		specs = strings.Split(os.Getenv("TEMPORAL_TARGETS"), ";")
+	}
+	targets, err := parseTargets(specs, target{
+		Namespace:   namespace,
+		TaskQueue:   taskQueue,
+		Concurrency: concurrentWorkflows,
+		Rate:        startRate,
+		ReadRate:    readRate,
+	})
+	if err != nil {
+		log.Fatalf("Invalid target: %v", err)
+	}
+
+	rSpecs := []string(rampSpecs)
+	if len(rSpecs) == 0 && os.Getenv("TEMPORAL_RAMPS") != "" {
+		rSpecs = strings.Split(os.Getenv("TEMPORAL_RAMPS"), ";")
+	}
+	rampSteps, err := parseRampSteps(rSpecs, rampStep{
+		Namespace:      namespace,
+		DeploymentName: getStringValue("deployment-name", "TEMPORAL_DEPLOYMENT_NAME", *sDeploymentName, ""),
+	})
+	if err != nil {
+		log.Fatalf("Invalid ramp: %v", err)
+	}
+	for _, s := range rampSteps {
+		if !slices.ContainsFunc(targets, func(t target) bool { return t.Namespace == s.Namespace }) {
+			log.Fatalf("Invalid ramp: no target in namespace %s", s.Namespace)
+		}
+	}
+
+	var input []interface{}
+	for _, a := range flag.Args() {
+		var i interface{}
+		err := json.Unmarshal([]byte(a), &i)
+		if err != nil {
+			log.Fatalln("Unable to parse input", err)
+		}
+		input = append(input, i)
+	}
+
+	wl := workload{
+		WorkflowType: workflowType,
+		Scenario:     scenario,
+		SignalType:   signalType,
+		Input:        input,
+		Wait:         waitForCompletion,
+
+		HistorySample:   historySample,
+		ReadOps:         ops,
+		ReadConcurrency: readConcurrency,
+
+		TaskQueueInterval: taskQueueInterval,
+
+		Ramps: rampSteps,
+	}
+	backoff := backoffOptions{
+		Disabled:    disableBackOff,
+		MaxInterval: maxInterval,
+		Factor:      factor,
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	if coordinatorAddr != "" {
+		if duration <= 0 {
+			log.Fatalf("A duration is required when coordinating agents")
+		}
+		if agents < 1 {
+			log.Fatalf("At least one agent is required")
+		}
+
+		co := newCoordinator(agents, shardTargets, targets, wl, duration)
+		results, err := co.run(ctx, coordinatorAddr)
+		if err != nil {
+			log.Fatal(err)
+		}
+		printResults(results)
+
+		if resultsFile != "" {
+			if err := writeResults(resultsFile, results); err != nil {
+				log.Fatalf("Unable to write results: %v", err)
+			}
+			log.Printf("Wrote results to %s", resultsFile)
+		}
+		return
+	}
 
 	log.Printf("Using namespace: %s", namespace)
 
 	clientOptions := client.Options{
-		HostPort:  os.Getenv("TEMPORAL_GRPC_ENDPOINT"),
 		Namespace: namespace,
 		Logger:    NewNopLogger(),
 	}
@@ -147,131 +361,420 @@ func main() {
 		clientOptions.ConnectionOptions.TLS = &tlsConfig
 	}
 
-	if os.Getenv("PROMETHEUS_ENDPOINT") != "" {
-		clientOptions.MetricsHandler = sdktally.NewMetricsHandler(newPrometheusScope(prometheus.Configuration{
-			ListenAddress: os.Getenv("PROMETHEUS_ENDPOINT"),
-			TimerType:     "histogram",
-		}))
+	pprofAddr := getStringValue("pprof", "PPROF_ENDPOINT", *sPprof, "")
+	if pprofAddr != "" {
+		profile.EnableContentionProfiles()
+		pprofCtx, stopPprof := context.WithCancel(context.Background())
+		defer stopPprof()
+		go profile.Serve(pprofCtx, pprofAddr)
+	}
+	if profileDir := getStringValue("profile-dir", "PROFILE_DIR", *sProfileDir, ""); profileDir != "" {
+		window := profile.Window{
+			Dir:      profileDir,
+			After:    getDurationValue("profile-after", "PROFILE_AFTER", *dProfileAfter, 0),
+			Duration: getDurationValue("profile-duration", "PROFILE_DURATION", *dProfileDuration, 30*time.Second),
+		}
+		if err := window.Validate(); err != nil {
+			log.Fatalf("Invalid profile window: %v", err)
+		}
+		defer window.Start()()
 	}
 
-	c, err := client.Dial(clientOptions)
-	if err != nil {
-		log.Fatalf("Unable to create client: %v", err)
+	pushgatewayURL := os.Getenv("PROMETHEUS_PUSHGATEWAY_URL")
+	remoteWriteURL := os.Getenv("PROMETHEUS_REMOTE_WRITE_URL")
+	metricsScope := tally.NoopScope
+	if os.Getenv("PROMETHEUS_ENDPOINT") != "" || pushgatewayURL != "" || remoteWriteURL != "" {
+		metricsConf, err := metrics.ConfigFromEnv()
+		if err != nil {
+			log.Fatalf("Invalid metrics configuration: %v", err)
+		}
+		registry := prom.NewRegistry()
+		if err := metrics.RegisterProcessMetrics(registry, "runner", metricsConf.Labels); err != nil {
+			log.Fatalf("Unable to register process metrics: %v", err)
+		}
+		// The runner's own latency histograms take configured buckets too.
+		if len(metricsConf.Buckets) > 0 {
+			latencyBuckets = tally.DurationBuckets(metricsConf.Buckets)
+		}
+		scope, closer := metrics.NewPrometheusScope(metricsConf, registry)
+		metricsScope = scope
+		clientOptions.MetricsHandler = sdktally.NewMetricsHandler(scope)
+
+		stopPush, err := metrics.StartPushing(registry, closer, "benchmark-runner")
+		if err != nil {
+			log.Fatalf("Invalid metrics configuration: %v", err)
+		}
+		defer stopPush()
 	}
-	defer c.Close()
 
-	log.Printf("Created client for namespace: %s", namespace)
+	if os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") != "" {
+		shutdown, err := telemetry.NewOpenTelemetry(context.Background(), "benchmark-runner")
+		if err != nil {
+			log.Fatalf("Unable to set up OpenTelemetry: %v", err)
+		}
+		defer func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := shutdown(ctx); err != nil {
+				log.Printf("Unable to flush OpenTelemetry exporters: %v", err)
+			}
+		}()
 
-	var input []interface{}
-	for _, a := range flag.Args() {
-		var i interface{}
-		err := json.Unmarshal([]byte(a), &i)
+		tracingInterceptor, err := opentelemetry.NewTracingInterceptor(opentelemetry.TracerOptions{})
 		if err != nil {
-			log.Fatalln("Unable to parse input", err)
+			log.Fatalf("Unable to create tracing interceptor: %v", err)
 		}
-		input = append(input, i)
+		clientOptions.Interceptors = append(clientOptions.Interceptors, tracingInterceptor)
+
+		// Prometheus takes precedence for SDK metrics if both are configured.
+		if clientOptions.MetricsHandler == nil {
+			clientOptions.MetricsHandler = opentelemetry.NewMetricsHandler(opentelemetry.MetricsHandlerOptions{})
+		}
+
+		log.Printf("Exporting OpenTelemetry data to %s", os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"))
 	}
 
-	pool := pond.New(concurrentWorkflows, 0)
-
-	var starter func() (client.WorkflowRun, error)
-
-	if signalType != "" {
-		starter = func() (client.WorkflowRun, error) {
-			wID := uuid.New()
-			return c.SignalWithStartWorkflow(
-				context.Background(),
-				wID,
-				signalType,
-				nil,
-				client.StartWorkflowOptions{
-					ID:        wID,
-					TaskQueue: taskQueue,
-				},
-				workflowType,
-				input...,
-			)
-		}
-	} else {
-		starter = func() (client.WorkflowRun, error) {
-			return c.ExecuteWorkflow(
-				context.Background(),
-				client.StartWorkflowOptions{
-					TaskQueue: taskQueue,
-				},
-				workflowType,
-				input...,
-			)
-		}
-	}
-
-	go (func() {
-		currentInterval := 1
-		errChan := make(chan error, concurrentWorkflows)
-		
-		for {
-			pool.Submit(func() {
-				wf, err := starter()
-				if err != nil {
-					fmt.Fprintf(os.Stderr, "Unable to start workflow: %v\n", err)
-					errChan <- err
-					return
+	// Each endpoint is treated as a separate cluster. With more than one, load
+	// follows whichever cluster each namespace is active in, which needs the
+	// name each cluster reports for itself.
+	var clusters []*cluster
+	endpoints := strings.Split(os.Getenv("TEMPORAL_GRPC_ENDPOINT"), ",")
+	for _, endpoint := range endpoints {
+		endpoint = strings.TrimSpace(endpoint)
+
+		endpointOptions := clientOptions
+		endpointOptions.HostPort = endpoint
+
+		c, err := client.Dial(endpointOptions)
+		if err != nil {
+			log.Fatalf("Unable to create client: %v", err)
+		}
+		defer c.Close()
+
+		cl := &cluster{Endpoint: endpoint, client: c}
+		if cl.Name, err = clusterName(context.Background(), c); err != nil {
+			if len(endpoints) > 1 {
+				log.Fatalf("Unable to get cluster info from %s: %v", endpoint, err)
+			}
+			log.Printf("Unable to get cluster info from %s, naming the cluster after its endpoint: %v", endpoint, err)
+			cl.Name = endpoint
+		}
+		for _, other := range clusters {
+			if other.Name == cl.Name {
+				log.Fatalf("Endpoints %s and %s are both cluster %s", other.Endpoint, endpoint, cl.Name)
+			}
+		}
+		clusters = append(clusters, cl)
+
+		log.Printf("Created client for namespace: %s on cluster: %s", namespace, cl.Name)
+	}
+
+	var agentName string
+	var startAt time.Time
+	if coordinatorURL != "" {
+		hostname, _ := os.Hostname()
+		agentName = fmt.Sprintf("%s-%d", hostname, os.Getpid())
+
+		log.Printf("Registering with coordinator %s as %s", coordinatorURL, agentName)
+		a, err := joinCoordinator(ctx, coordinatorURL, agentName)
+		if err != nil {
+			log.Fatalf("Unable to join coordinator: %v", err)
+		}
+
+		targets, wl, duration, startAt = a.Targets, a.Workload, a.Duration, a.StartAt
+		log.Printf("Assigned agent %d of %d, starting at %s", a.Agent+1, a.Agents, startAt.Format(time.RFC3339))
+	}
+
+	var generators []*loadGenerator
+	for _, t := range targets {
+		targetOptions := client.Options{
+			Namespace:    t.Namespace,
+			Logger:       clientOptions.Logger,
+			Interceptors: clientOptions.Interceptors,
+		}
+		if clientOptions.MetricsHandler != nil {
+			targetOptions.MetricsHandler = clientOptions.MetricsHandler.WithTags(map[string]string{"benchmark_target": t.Label})
+		}
+
+		var targetClusters []*cluster
+		for _, cl := range clusters {
+			tc, err := client.NewClientFromExisting(cl.client, targetOptions)
+			if err != nil {
+				log.Fatalf("Unable to create client for target %s: %v", t.Label, err)
+			}
+			defer tc.Close()
+
+			targetClusters = append(targetClusters, &cluster{Name: cl.Name, Endpoint: cl.Endpoint, client: tc})
+		}
+		cc := newClusterClient(t.Namespace, targetClusters)
+
+		if embeddedWorker {
+			for _, cl := range cc.Clusters() {
+				w := worker.New(cl.client, t.TaskQueue, worker.Options{})
+
+				workflows.Register(w)
+				activities.Register(w)
+
+				if err := w.Start(); err != nil {
+					log.Fatalf("Unable to start embedded worker: %v", err)
 				}
-				
-				if waitForCompletion {
-					err = wf.Get(context.Background(), nil)
-					if err != nil {
-						fmt.Fprintf(os.Stderr, "Workflow failed: %v\n", err)
-						errChan <- err
-						return
-					}
+				defer w.Stop()
+
+				log.Printf("Started embedded worker on namespace: %s task queue: %s cluster: %s", t.Namespace, t.TaskQueue, cl.Name)
+			}
+		}
+
+		if t.Rate > 0 {
+			log.Printf("Target %s: concurrency %d, rate %.2f/s", t.Label, t.Concurrency, t.Rate)
+		} else {
+			log.Printf("Target %s: concurrency %d", t.Label, t.Concurrency)
+		}
+
+		generators = append(generators, newLoadGenerator(t, cc, wl, backoff, metricsScope))
+	}
+
+	ctx, stopRun := context.WithCancel(ctx)
+	defer stopRun()
+
+	ctrl := newController(generators, stopRun)
+	if controlAddr != "" {
+		serverCtx, stopServer := context.WithCancel(context.Background())
+		defer stopServer()
+		go ctrl.serve(serverCtx, controlAddr)
+	}
+
+	closeDashboard := func() {}
+	if showDashboard {
+		buf := &messageBuffer{}
+		messages = buf
+		log.SetOutput(buf)
+
+		dashboardCtx, stopDashboard := context.WithCancel(context.Background())
+		dashboardDone := make(chan struct{})
+		dash := &dashboard{ctrl: ctrl, generators: generators, messages: buf, out: os.Stdout}
+		go func() {
+			defer close(dashboardDone)
+			dash.run(dashboardCtx)
+		}()
+
+		closeDashboard = sync.OnceFunc(func() {
+			stopDashboard()
+			<-dashboardDone
+			messages = os.Stderr
+			log.SetOutput(os.Stderr)
+		})
+		defer closeDashboard()
+	}
+
+	select {
+	case <-time.After(time.Until(startAt)):
+	case <-ctx.Done():
+	}
+
+	if duration > 0 {
+		ctx, cancel = context.WithTimeout(ctx, duration)
+		defer cancel()
+	}
+
+	startTime := time.Now()
+	ctrl.setPhase(phaseRunning)
+
+	// Under a coordinator, each ramp step is assigned to one agent.
+	var ramper *rampRunner
+	if len(wl.Ramps) > 0 {
+		clients := make(map[string]*clusterClient)
+		for _, g := range generators {
+			clients[g.target.Namespace] = g.clients
+		}
+		ramper = newRampRunner(wl.Ramps, clients)
+		go ramper.run(ctx, startTime)
+	}
+
+	var wg sync.WaitGroup
+	for _, g := range generators {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			g.run(ctx)
+		}()
+		go g.clients.watch(ctx, failoverCheckInterval)
+	}
+
+	lastCompleted := make([]uint64, len(generators))
+	lastCheck := time.Now()
+	ticker := time.NewTicker(10 * time.Second)
+	defer ticker.Stop()
+
+reportLoop:
+	for {
+		// The dashboard shows live status itself.
+		if !showDashboard {
+			elapsed := time.Since(lastCheck).Seconds()
+			for i, g := range generators {
+				completed := g.finished()
+				rate := float64(completed-lastCompleted[i]) / elapsed
+
+				if len(generators) > 1 {
+					fmt.Printf("[%s] ", g.target.Label)
 				}
-				
-				errChan <- nil
-			})
-			
-			var lastErr error
-			updated := false
-			
-			drainLoop:
-			for {
-				select {
-				case err := <-errChan:
-					lastErr = err
-					updated = true
-				default:
-					break drainLoop
+				fmt.Printf("Concurrent: %d Workflows: %d Rate: %f\n", g.inFlight(), completed, rate)
+				if g.queues != nil {
+					stats := g.queues.stats()
+					for _, tqType := range taskQueueTypes {
+						if s, ok := stats[tqType]; ok {
+							fmt.Printf("  Task queue %s: %s\n", tqType, formatTaskQueueStats(s))
+						}
+					}
 				}
+
+				lastCompleted[i] = completed
 			}
-			
-			if disableBackOff || !updated {
+		}
+		lastCheck = time.Now()
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			break reportLoop
+		}
+	}
+
+	log.Printf("Stopping, waiting for in-flight workflows")
+	ctrl.setPhase(phaseStopping)
+	wg.Wait()
+	ctrl.setPhase(phaseFinished)
+	closeDashboard()
+
+	elapsed := time.Since(startTime)
+	results := runResults{
+		Duration: elapsed.Round(time.Millisecond).String(),
+	}
+	histograms := make(map[string]*hdrhistogram.Snapshot)
+	correctedHistograms := make(map[string]*hdrhistogram.Snapshot)
+	readHistograms := make(map[string]map[string]*hdrhistogram.Snapshot)
+	for _, g := range generators {
+		results.Targets = append(results.Targets, g.results(elapsed))
+		histograms[g.target.Label] = g.latency.snapshot()
+		correctedHistograms[g.target.Label] = g.corrected.snapshot()
+		if g.reads != nil {
+			readHistograms[g.target.Label] = g.reads.snapshots()
+		}
+	}
+	if ramper != nil {
+		results.Ramps = ramper.results()
+	}
+	printResults(results)
+
+	if coordinatorURL != "" {
+		report := agentReport{
+			Agent:               agentName,
+			Results:             results,
+			Histograms:          histograms,
+			CorrectedHistograms: correctedHistograms,
+			ReadHistograms:      readHistograms,
+		}
+		if err := sendReport(context.Background(), coordinatorURL, report); err != nil {
+			log.Fatalf("Unable to report results to coordinator: %v", err)
+		}
+		log.Printf("Reported results to coordinator")
+	}
+
+	if resultsFile != "" {
+		if err := writeResults(resultsFile, results); err != nil {
+			log.Fatalf("Unable to write results: %v", err)
+		}
+		log.Printf("Wrote results to %s", resultsFile)
+	}
+}
+
+func printResults(results runResults) {
+	for _, r := range results.Targets {
+		if len(results.Targets) > 1 {
+			fmt.Printf("[%s] ", r.Label)
+		}
+		fmt.Printf("Started: %d Completed: %d Failed: %d Start failures: %d Rate: %f\n",
+			r.Started, r.Completed, r.Failed, r.StartFailed, r.Rate)
+		if r.Latency.Count > 0 {
+			fmt.Printf("  Latency: p50 %s p90 %s p99 %s max %s\n", r.Latency.P50, r.Latency.P90, r.Latency.P99, r.Latency.Max)
+		}
+		if r.CorrectedLatency.Count > 0 {
+			fmt.Printf("  Corrected latency: p50 %s p90 %s p99 %s max %s\n",
+				r.CorrectedLatency.P50, r.CorrectedLatency.P90, r.CorrectedLatency.P99, r.CorrectedLatency.Max)
+		}
+
+		if h := r.History; h != nil {
+			fmt.Printf("  History (%d sampled): events mean %.1f max %d, bytes mean %.0f max %d\n",
+				h.Sampled, h.MeanEvents, h.MaxEvents, h.MeanBytes, h.MaxBytes)
+			fmt.Printf("  Events per workflow: %s\n", formatEventTypes(h.EventTypes))
+			if h.Dropped > 0 {
+				fmt.Printf("  History samples dropped: %d (more than %d fetches in progress)\n", h.Dropped, maxHistoryFetches)
+			}
+		}
+
+		for _, op := range readOps {
+			rs, ok := r.Reads[op]
+			if !ok {
 				continue
 			}
-			
-			if lastErr != nil {
-				fmt.Fprintf(os.Stderr, "Waiting for %d seconds before retrying to start workflow...\n", currentInterval)
-				time.Sleep(time.Duration(currentInterval) * time.Second)
-				nInterval := currentInterval * factor
-				if nInterval < maxInterval && maxInterval != 0 {
-					currentInterval *= factor
-				}
-			} else if lastErr == nil {
-				currentInterval = 1
+			fmt.Printf("  Reads %s: calls %d errors %d rate %f bytes mean %.0f", op, rs.Calls, rs.Errors, rs.Rate, rs.MeanBytes)
+			if rs.Latency.Count > 0 {
+				fmt.Printf(" latency p50 %s p99 %s max %s", rs.Latency.P50, rs.Latency.P99, rs.Latency.Max)
 			}
+			fmt.Println()
 		}
-	})()
 
-	var lastCompleted uint64
-	lastCheck := time.Now()
+		for _, tqType := range taskQueueTypes {
+			s, ok := r.TaskQueueStats[tqType]
+			if !ok {
+				continue
+			}
+			fmt.Printf("  Task queue %s: pollers min %d max %d, backlog max %d age max %s, add max %.1f/s dispatch max %.1f/s\n",
+				tqType, s.MinPollers, s.MaxPollers, s.MaxBacklogCount, s.MaxBacklogAge, s.MaxAddRate, s.MaxDispatchRate)
+		}
 
-	for {
-		rate := float64(pool.CompletedTasks()-lastCompleted) / time.Since(lastCheck).Seconds()
+		for _, o := range r.Outages {
+			fmt.Printf("  Outage: %s to %s (%s, %d failed starts)\n",
+				o.Start.Format(time.RFC3339), o.End.Format(time.RFC3339), o.Duration, o.FailedStarts)
+		}
+		for _, f := range r.Failovers {
+			fmt.Printf("  Failover: %s from %s to %s, latency baseline %s peak %s\n",
+				f.Time.Format(time.RFC3339), f.From, f.To, f.BaselineLatency, f.PeakLatency)
+		}
+	}
 
-		fmt.Printf("Concurrent: %d Workflows: %d Rate: %f\n", pool.RunningWorkers(), pool.CompletedTasks(), rate)
+	for _, e := range results.Ramps {
+		version := e.BuildID
+		if version == "" {
+			version = "unversioned"
+		}
+		change := fmt.Sprintf("ramping %.1f%% to %s", e.Percentage, version)
+		if e.Current {
+			change = "current version " + version
+		}
+		fmt.Printf("Deployment %s: %s %s", e.DeploymentName, change, e.Time.Format(time.RFC3339))
+		if e.Error != "" {
+			fmt.Printf(" failed: %s", e.Error)
+		}
+		fmt.Println()
+	}
+}
 
-		lastCheck = time.Now()
-		lastCompleted = pool.CompletedTasks()
+// formatEventTypes lists event types by their mean count, most common first.
+func formatEventTypes(eventTypes map[string]float64) string {
+	types := make([]string, 0, len(eventTypes))
+	for t := range eventTypes {
+		types = append(types, t)
+	}
+	sort.Slice(types, func(i, j int) bool {
+		if eventTypes[types[i]] != eventTypes[types[j]] {
+			return eventTypes[types[i]] > eventTypes[types[j]]
+		}
+		return types[i] < types[j]
+	})
 
-		time.Sleep(10 * time.Second)
+	parts := make([]string, len(types))
+	for i, t := range types {
+		parts[i] = fmt.Sprintf("%s %.1f", t, eventTypes[t])
 	}
+	return strings.Join(parts, ", ")
 }
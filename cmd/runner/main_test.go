@@ -0,0 +1,351 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/temporalio/benchmark-workers/activities"
+	"github.com/temporalio/benchmark-workers/internal/devserver"
+	"github.com/temporalio/benchmark-workers/workflows"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/worker"
+	"go.temporal.io/sdk/workflow"
+	"google.golang.org/protobuf/proto"
+)
+
+func buildRunner(t *testing.T) string {
+	bin := filepath.Join(t.TempDir(), "runner")
+	out, err := exec.Command("go", "build", "-o", bin, ".").CombinedOutput()
+	require.NoError(t, err, string(out))
+	return bin
+}
+
+func TestRunnerEmbeddedWorker(t *testing.T) {
+	server := devserver.Start(t)
+	bin := buildRunner(t)
+	resultsFile := filepath.Join(t.TempDir(), "results.json")
+
+	cmd := exec.Command(bin,
+		"-embedded-worker",
+		"-c", "2",
+		"-d", "5s",
+		"-history-sample", "1",
+		"-task-queue-stats-interval", "1s",
+		"-o", resultsFile,
+		"-t", "ExecuteActivity",
+		`{"Count": 1, "Activity": "Echo", "Input": {"Message": "test"}}`,
+	)
+	cmd.Env = append(os.Environ(), "TEMPORAL_GRPC_ENDPOINT="+server.FrontendHostPort())
+	out, err := cmd.CombinedOutput()
+	require.NoError(t, err, string(out))
+
+	b, err := os.ReadFile(resultsFile)
+	require.NoError(t, err)
+
+	var results runResults
+	require.NoError(t, json.Unmarshal(b, &results))
+	require.Len(t, results.Targets, 1)
+	require.Equal(t, "ExecuteActivity", results.Targets[0].WorkflowType)
+	require.Greater(t, results.Targets[0].Completed, uint64(0))
+	require.Zero(t, results.Targets[0].Failed)
+	require.Zero(t, results.Targets[0].StartFailed)
+
+	// Every execution's history was measured: one activity gives 11 events.
+	h := results.Targets[0].History
+	require.NotNil(t, h)
+	require.Equal(t, int64(results.Targets[0].Completed), h.Sampled)
+	require.Equal(t, 11.0, h.MeanEvents)
+	require.Equal(t, 1.0, h.EventTypes["ActivityTaskCompleted"])
+	require.Greater(t, h.MeanBytes, 0.0)
+
+	// The embedded worker polls the task queue.
+	require.GreaterOrEqual(t, results.Targets[0].TaskQueueStats["workflow"].MinPollers, 1)
+	require.GreaterOrEqual(t, results.Targets[0].TaskQueueStats["activity"].MinPollers, 1)
+}
+
+func TestRunnerOpenTelemetry(t *testing.T) {
+	server := devserver.Start(t)
+	bin := buildRunner(t)
+
+	// A stand-in OTLP/HTTP collector that records the names of the spans in
+	// each trace.
+	var mu sync.Mutex
+	traces := make(map[string]map[string]bool)
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/traces" {
+			return
+		}
+		b, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+
+		var req coltracepb.ExportTraceServiceRequest
+		require.NoError(t, proto.Unmarshal(b, &req))
+
+		mu.Lock()
+		defer mu.Unlock()
+		for _, rs := range req.ResourceSpans {
+			for _, ss := range rs.ScopeSpans {
+				for _, span := range ss.Spans {
+					id := string(span.TraceId)
+					if traces[id] == nil {
+						traces[id] = make(map[string]bool)
+					}
+					traces[id][span.Name] = true
+				}
+			}
+		}
+	}))
+	defer collector.Close()
+
+	cmd := exec.Command(bin,
+		"-embedded-worker",
+		"-c", "2",
+		"-d", "3s",
+		"-t", "ExecuteActivity",
+		`{"Count": 1, "Activity": "Echo", "Input": {"Message": "test"}}`,
+	)
+	cmd.Env = append(os.Environ(),
+		"TEMPORAL_GRPC_ENDPOINT="+server.FrontendHostPort(),
+		"OTEL_EXPORTER_OTLP_ENDPOINT="+collector.URL,
+		"OTEL_EXPORTER_OTLP_PROTOCOL=http/protobuf",
+	)
+	out, err := cmd.CombinedOutput()
+	require.NoError(t, err, string(out))
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	// At least one trace covers the whole execution.
+	complete := false
+	for _, spans := range traces {
+		if spans["BenchmarkExecution:ExecuteActivity"] &&
+			spans["StartWorkflow:ExecuteActivity"] &&
+			spans["RunWorkflow:ExecuteActivity"] &&
+			spans["RunActivity:Echo"] {
+			complete = true
+		}
+	}
+	require.True(t, complete, "no complete trace in %v", traces)
+}
+
+func TestRunnerMultipleTargets(t *testing.T) {
+	server := devserver.Start(t)
+	bin := buildRunner(t)
+	resultsFile := filepath.Join(t.TempDir(), "results.json")
+
+	cmd := exec.Command(bin,
+		"-embedded-worker",
+		"-d", "5s",
+		"-o", resultsFile,
+		"-target", "tq=closed,c=2",
+		"-target", "tq=paced,c=2,r=5,label=paced",
+		"-t", "ExecuteActivity",
+		`{"Count": 1, "Activity": "Echo", "Input": {"Message": "test"}}`,
+	)
+	cmd.Env = append(os.Environ(), "TEMPORAL_GRPC_ENDPOINT="+server.FrontendHostPort())
+	out, err := cmd.CombinedOutput()
+	require.NoError(t, err, string(out))
+
+	b, err := os.ReadFile(resultsFile)
+	require.NoError(t, err)
+
+	var results runResults
+	require.NoError(t, json.Unmarshal(b, &results))
+	require.Len(t, results.Targets, 2)
+	require.Equal(t, "default/closed", results.Targets[0].Label)
+	require.Equal(t, "paced", results.Targets[1].Label)
+	for _, r := range results.Targets {
+		require.Greater(t, r.Completed, uint64(0), r.Label)
+		require.Zero(t, r.Failed, r.Label)
+	}
+	// Starts against the paced target are limited to roughly 5 per second.
+	require.LessOrEqual(t, results.Targets[1].Started, uint64(40))
+}
+
+func TestRunnerDeploymentRamp(t *testing.T) {
+	server := devserver.Start(t)
+	bin := buildRunner(t)
+	resultsFile := filepath.Join(t.TempDir(), "results.json")
+
+	// Two versions of the benchmark worker, side by side.
+	for _, buildID := range []string{"v1", "v2"} {
+		w := worker.New(server.Client(), "benchmark", worker.Options{
+			DeploymentOptions: worker.DeploymentOptions{
+				UseVersioning:             true,
+				Version:                   worker.WorkerDeploymentVersion{DeploymentName: "benchmark", BuildID: buildID},
+				DefaultVersioningBehavior: workflow.VersioningBehaviorAutoUpgrade,
+			},
+		})
+		workflows.Register(w)
+		activities.Register(w)
+		require.NoError(t, w.Start())
+		t.Cleanup(w.Stop)
+	}
+
+	cmd := exec.Command(bin,
+		"-c", "2",
+		"-d", "8s",
+		"-o", resultsFile,
+		"-deployment-name", "benchmark",
+		"-ramp", "at=0s,build-id=v1",
+		"-ramp", "at=3s,build-id=v2,percentage=50",
+		"-ramp", "at=5s,build-id=v2",
+		"-t", "ExecuteActivity",
+		`{"Count": 1, "Activity": "Echo", "Input": {"Message": "test"}}`,
+	)
+	cmd.Env = append(os.Environ(), "TEMPORAL_GRPC_ENDPOINT="+server.FrontendHostPort())
+	out, err := cmd.CombinedOutput()
+	require.NoError(t, err, string(out))
+
+	b, err := os.ReadFile(resultsFile)
+	require.NoError(t, err)
+
+	var results runResults
+	require.NoError(t, json.Unmarshal(b, &results))
+	require.Greater(t, results.Targets[0].Completed, uint64(0))
+	require.Zero(t, results.Targets[0].Failed)
+	require.Len(t, results.Ramps, 3)
+	for _, e := range results.Ramps {
+		require.Empty(t, e.Error)
+	}
+	require.False(t, results.Ramps[1].Current)
+	require.Equal(t, float32(50), results.Ramps[1].Percentage)
+
+	desc, err := server.Client().WorkerDeploymentClient().GetHandle("benchmark").Describe(context.Background(), client.WorkerDeploymentDescribeOptions{})
+	require.NoError(t, err)
+	require.Equal(t, "v2", desc.Info.RoutingConfig.CurrentVersion.BuildID)
+}
+
+func TestRunnerCoordinatedAgents(t *testing.T) {
+	server := devserver.Start(t)
+	bin := buildRunner(t)
+	resultsFile := filepath.Join(t.TempDir(), "results.json")
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := l.Addr().String()
+	l.Close()
+
+	coordinator := exec.Command(bin,
+		"-coordinator", addr,
+		"-agents", "2",
+		"-c", "4",
+		"-r", "20",
+		"-d", "4s",
+		"-o", resultsFile,
+		"-t", "ExecuteActivity",
+		`{"Count": 1, "Activity": "Echo", "Input": {"Message": "test"}}`,
+	)
+	var coordinatorOut bytes.Buffer
+	coordinator.Stdout = &coordinatorOut
+	coordinator.Stderr = &coordinatorOut
+	require.NoError(t, coordinator.Start())
+
+	var agents []*exec.Cmd
+	for i := 0; i < 2; i++ {
+		agent := exec.Command(bin, "-embedded-worker", "-agent", "http://"+addr)
+		agent.Env = append(os.Environ(), "TEMPORAL_GRPC_ENDPOINT="+server.FrontendHostPort())
+		require.NoError(t, agent.Start())
+		agents = append(agents, agent)
+	}
+
+	for _, agent := range agents {
+		require.NoError(t, agent.Wait())
+	}
+	require.NoError(t, coordinator.Wait(), coordinatorOut.String())
+
+	b, err := os.ReadFile(resultsFile)
+	require.NoError(t, err)
+
+	var results runResults
+	require.NoError(t, json.Unmarshal(b, &results))
+	require.Len(t, results.Targets, 1)
+	r := results.Targets[0]
+	require.Equal(t, 4, r.ConcurrentWorkflows)
+	require.Greater(t, r.Completed, uint64(0))
+	require.Zero(t, r.Failed)
+	require.Equal(t, int64(r.Completed), r.Latency.Count)
+	require.Equal(t, int64(r.Completed), r.CorrectedLatency.Count)
+}
+
+func TestRunnerControlAPI(t *testing.T) {
+	server := devserver.Start(t)
+	bin := buildRunner(t)
+	resultsFile := filepath.Join(t.TempDir(), "results.json")
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := l.Addr().String()
+	l.Close()
+
+	cmd := exec.Command(bin,
+		"-embedded-worker",
+		"-control", addr,
+		"-c", "2",
+		"-o", resultsFile,
+		"-t", "ExecuteActivity",
+		`{"Count": 1, "Activity": "Echo", "Input": {"Message": "test"}}`,
+	)
+	cmd.Env = append(os.Environ(), "TEMPORAL_GRPC_ENDPOINT="+server.FrontendHostPort())
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	require.NoError(t, cmd.Start())
+	t.Cleanup(func() { cmd.Process.Kill() })
+
+	status := func(method, path string) runnerStatus {
+		req, err := http.NewRequest(method, "http://"+addr+path, nil)
+		require.NoError(t, err)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var s runnerStatus
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&s))
+		return s
+	}
+
+	require.Eventually(t, func() bool {
+		resp, err := http.Get("http://" + addr + "/status")
+		if err != nil {
+			return false
+		}
+		defer resp.Body.Close()
+
+		var s runnerStatus
+		return json.NewDecoder(resp.Body).Decode(&s) == nil && s.Targets[0].Completed > 0
+	}, 30*time.Second, 100*time.Millisecond)
+
+	s := status("POST", "/concurrency?value=5")
+	require.Equal(t, 5, s.Targets[0].Concurrency)
+
+	s = status("POST", "/rate?value=20")
+	require.Equal(t, 20.0, s.Targets[0].TargetRate)
+
+	s = status("POST", "/pause")
+	require.Equal(t, phasePaused, s.Phase)
+	require.True(t, s.Targets[0].Paused)
+
+	s = status("POST", "/resume")
+	require.Equal(t, phaseRunning, s.Phase)
+
+	status("POST", "/stop")
+	require.NoError(t, cmd.Wait(), out.String())
+
+	_, err = os.Stat(resultsFile)
+	require.NoError(t, err)
+}
@@ -1,34 +1,80 @@
 package main
 
 import (
-	"log"
 	"time"
 
-	prom "github.com/prometheus/client_golang/prometheus"
 	"github.com/uber-go/tally/v4"
-	"github.com/uber-go/tally/v4/prometheus"
-	sdktally "go.temporal.io/sdk/contrib/tally"
 )
 
-func newPrometheusScope(c prometheus.Configuration) tally.Scope {
-	reporter, err := c.NewReporter(
-		prometheus.ConfigurationOptions{
-			Registry: prom.NewRegistry(),
-			OnError: func(err error) {
-				log.Println("error in prometheus reporter", err)
-			},
-		},
-	)
-	if err != nil {
-		log.Fatalln("error creating prometheus reporter", err)
-	}
-	scopeOpts := tally.ScopeOptions{
-		CachedReporter:  reporter,
-		Separator:       prometheus.DefaultSeparator,
-		SanitizeOptions: &sdktally.PrometheusSanitizeOptions,
+// latencyBuckets are the completion latency histogram buckets, from 10ms to
+// about 80s unless PROMETHEUS_HISTOGRAM_BUCKETS is set.
+var latencyBuckets = tally.MustMakeExponentialDurationBuckets(10*time.Millisecond, 2, 14)
+
+// generatorMetrics are the runner's own metrics for a target, so that the load
+// offered can be graphed against the load achieved.
+type generatorMetrics struct {
+	scope tally.Scope
+
+	startsAttempted   tally.Counter
+	startsSucceeded   tally.Counter
+	completed         tally.Counter
+	backoffSleeps     tally.Counter
+	inFlight          tally.Gauge
+	targetConcurrency tally.Gauge
+	targetRate        tally.Gauge
+	latency           tally.Histogram
+	correctedLatency  tally.Histogram
+}
+
+func newGeneratorMetrics(scope tally.Scope, t target, w workload) *generatorMetrics {
+	scope = scope.Tagged(map[string]string{
+		"benchmark_target": t.Label,
+		"workflow_type":    w.WorkflowType,
+		"scenario":         w.Scenario,
+	})
+	return &generatorMetrics{
+		scope:             scope,
+		startsAttempted:   scope.Counter("benchmark_starts_attempted"),
+		startsSucceeded:   scope.Counter("benchmark_starts_succeeded"),
+		completed:         scope.Counter("benchmark_executions_completed"),
+		backoffSleeps:     scope.Counter("benchmark_backoff_sleeps"),
+		inFlight:          scope.Gauge("benchmark_in_flight"),
+		targetConcurrency: scope.Gauge("benchmark_target_concurrency"),
+		targetRate:        scope.Gauge("benchmark_target_rate"),
+		latency:           scope.Histogram("benchmark_completion_latency", latencyBuckets),
+		correctedLatency:  scope.Histogram("benchmark_corrected_completion_latency", latencyBuckets),
 	}
-	scope, _ := tally.NewRootScope(scopeOpts, time.Second)
+}
+
+func (m *generatorMetrics) startFailed(err error) {
+	m.scope.Tagged(map[string]string{"error_class": classifyError(err)}).Counter("benchmark_starts_failed").Inc(1)
+}
+
+func (m *generatorMetrics) executionFailed(err error) {
+	m.scope.Tagged(map[string]string{"error_class": classifyError(err)}).Counter("benchmark_executions_failed").Inc(1)
+}
+
+func (m *generatorMetrics) read(op string, bytes int) {
+	scope := m.scope.Tagged(map[string]string{"operation": op})
+	scope.Counter("benchmark_reads").Inc(1)
+	scope.Counter("benchmark_read_bytes").Inc(int64(bytes))
+}
+
+func (m *generatorMetrics) readLatency(op string, latency time.Duration) {
+	m.scope.Tagged(map[string]string{"operation": op}).Histogram("benchmark_read_latency", latencyBuckets).RecordDuration(latency)
+}
+
+// taskQueue records the latest stats of one type of task on the target's task
+// queue.
+func (m *generatorMetrics) taskQueue(tqType string, s taskQueueStats, backlogAge time.Duration) {
+	scope := m.scope.Tagged(map[string]string{"task_queue_type": tqType})
+	scope.Gauge("benchmark_task_queue_pollers").Update(float64(s.Pollers))
+	scope.Gauge("benchmark_task_queue_backlog_count").Update(float64(s.BacklogCount))
+	scope.Gauge("benchmark_task_queue_backlog_age").Update(backlogAge.Seconds())
+	scope.Gauge("benchmark_task_queue_add_rate").Update(s.AddRate)
+	scope.Gauge("benchmark_task_queue_dispatch_rate").Update(s.DispatchRate)
+}
 
-	log.Println("prometheus metrics scope created")
-	return scope
+func (m *generatorMetrics) readFailed(op string, err error) {
+	m.scope.Tagged(map[string]string{"operation": op, "error_class": classifyError(err)}).Counter("benchmark_reads_failed").Inc(1)
 }
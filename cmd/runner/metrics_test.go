@@ -0,0 +1,24 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/uber-go/tally/v4"
+	"go.temporal.io/api/serviceerror"
+)
+
+func TestGeneratorMetrics(t *testing.T) {
+	scope := tally.NewTestScope("", nil)
+	m := newGeneratorMetrics(scope, target{Label: "default/benchmark"}, workload{WorkflowType: "ExecuteActivity", Scenario: "echo"})
+
+	m.startsAttempted.Inc(2)
+	m.startsSucceeded.Inc(1)
+	m.startFailed(serviceerror.NewResourceExhausted(0, "busy"))
+
+	counters := scope.Snapshot().Counters()
+	tags := "benchmark_target=default/benchmark,scenario=echo,workflow_type=ExecuteActivity"
+	require.EqualValues(t, 2, counters["benchmark_starts_attempted+"+tags].Value())
+	require.EqualValues(t, 1, counters["benchmark_starts_succeeded+"+tags].Value())
+	require.EqualValues(t, 1, counters["benchmark_starts_failed+benchmark_target=default/benchmark,error_class=ResourceExhausted,scenario=echo,workflow_type=ExecuteActivity"].Value())
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.temporal.io/sdk/client"
+)
+
+// rampStep changes the routing of a worker deployment at a point in the run:
+// either making a version current, or ramping a percentage of new workflows
+// and auto-upgrade workflow tasks to it.
+type rampStep struct {
+	At             time.Duration `json:"at"`
+	Namespace      string        `json:"namespace"`
+	DeploymentName string        `json:"deploymentName"`
+	// BuildID is the version to route to. Empty means unversioned workers.
+	BuildID string `json:"buildId"`
+	// Current makes the version current, ending any ramp. Otherwise
+	// Percentage of tasks are ramped to it.
+	Current    bool    `json:"current"`
+	Percentage float32 `json:"percentage"`
+}
+
+// rampList collects repeated -ramp flags.
+type rampList []string
+
+func (l *rampList) String() string {
+	return strings.Join(*l, ";")
+}
+
+func (l *rampList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// parseRampStep parses a ramp spec of comma-separated key=value pairs, e.g.
+// "at=2m,build-id=v2,percentage=25". Without a percentage the version is made
+// current. Keys not given are taken from defaults.
+func parseRampStep(spec string, defaults rampStep) (rampStep, error) {
+	s := defaults
+	s.Current = true
+
+	for _, kv := range strings.Split(spec, ",") {
+		kv = strings.TrimSpace(kv)
+		if kv == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(kv, "=")
+		if !ok {
+			return s, fmt.Errorf("invalid ramp option %q, expected key=value", kv)
+		}
+
+		switch key {
+		case "at":
+			d, err := time.ParseDuration(value)
+			if err != nil || d < 0 {
+				return s, fmt.Errorf("invalid ramp time %q", value)
+			}
+			s.At = d
+		case "n", "namespace":
+			s.Namespace = value
+		case "deployment", "deployment-name":
+			s.DeploymentName = value
+		case "build-id":
+			s.BuildID = value
+		case "percentage":
+			p, err := strconv.ParseFloat(value, 32)
+			if err != nil || p < 0 || p > 100 {
+				return s, fmt.Errorf("invalid ramp percentage %q, expected 0 to 100", value)
+			}
+			s.Current = false
+			s.Percentage = float32(p)
+		default:
+			return s, fmt.Errorf("unknown ramp option %q", key)
+		}
+	}
+
+	if s.DeploymentName == "" {
+		return s, fmt.Errorf("ramp %q needs a deployment name", spec)
+	}
+	return s, nil
+}
+
+// parseRampSteps parses each spec, returning the steps in the order they
+// happen.
+func parseRampSteps(specs []string, defaults rampStep) ([]rampStep, error) {
+	var steps []rampStep
+	for _, spec := range specs {
+		s, err := parseRampStep(spec, defaults)
+		if err != nil {
+			return nil, err
+		}
+		steps = append(steps, s)
+	}
+	sort.SliceStable(steps, func(i, j int) bool {
+		return steps[i].At < steps[j].At
+	})
+	return steps, nil
+}
+
+// String describes the step for logging.
+func (s rampStep) String() string {
+	version := s.BuildID
+	if version == "" {
+		version = "unversioned workers"
+	}
+	if s.Current {
+		return fmt.Sprintf("deployment %s current version %s", s.DeploymentName, version)
+	}
+	return fmt.Sprintf("deployment %s ramping %.1f%% to %s", s.DeploymentName, s.Percentage, version)
+}
+
+// rampEvent records a ramp step being applied.
+type rampEvent struct {
+	Time           time.Time `json:"time"`
+	DeploymentName string    `json:"deploymentName"`
+	BuildID        string    `json:"buildId"`
+	Current        bool      `json:"current"`
+	Percentage     float32   `json:"percentage,omitempty"`
+	// Error is set if the step couldn't be applied before the run ended.
+	Error string `json:"error,omitempty"`
+}
+
+// rampInterval is how often a ramp step that fails, for example because the
+// version's workers haven't polled yet, is retried.
+const rampInterval = time.Second
+
+// rampRunner applies ramp steps at their times into the run.
+type rampRunner struct {
+	steps   []rampStep
+	clients map[string]*clusterClient
+
+	mu     sync.Mutex
+	events []rampEvent
+}
+
+func newRampRunner(steps []rampStep, clients map[string]*clusterClient) *rampRunner {
+	return &rampRunner{steps: steps, clients: clients}
+}
+
+// run applies each step in turn until they are all applied or ctx is done.
+func (r *rampRunner) run(ctx context.Context, start time.Time) {
+	for _, s := range r.steps {
+		select {
+		case <-time.After(time.Until(start.Add(s.At))):
+		case <-ctx.Done():
+			return
+		}
+
+		err := r.apply(ctx, s)
+		event := rampEvent{
+			Time:           time.Now(),
+			DeploymentName: s.DeploymentName,
+			BuildID:        s.BuildID,
+			Current:        s.Current,
+			Percentage:     s.Percentage,
+		}
+		if err != nil {
+			event.Error = err.Error()
+			fmt.Fprintf(messages, "Unable to set %s: %v\n", s, err)
+		} else {
+			log.Printf("Set %s", s)
+		}
+
+		r.mu.Lock()
+		r.events = append(r.events, event)
+		r.mu.Unlock()
+	}
+}
+
+// apply retries the step until it succeeds or ctx is done.
+func (r *rampRunner) apply(ctx context.Context, s rampStep) error {
+	cc, ok := r.clients[s.Namespace]
+	if !ok {
+		return fmt.Errorf("no target in namespace %s", s.Namespace)
+	}
+
+	for {
+		handle := cc.Client().WorkerDeploymentClient().GetHandle(s.DeploymentName)
+		var err error
+		if s.Current {
+			_, err = handle.SetCurrentVersion(ctx, client.WorkerDeploymentSetCurrentVersionOptions{BuildID: s.BuildID})
+		} else {
+			_, err = handle.SetRampingVersion(ctx, client.WorkerDeploymentSetRampingVersionOptions{BuildID: s.BuildID, Percentage: s.Percentage})
+		}
+		if err == nil {
+			return nil
+		}
+
+		select {
+		case <-time.After(rampInterval):
+		case <-ctx.Done():
+			return err
+		}
+	}
+}
+
+// results returns the steps applied so far.
+func (r *rampRunner) results() []rampEvent {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]rampEvent(nil), r.events...)
+}
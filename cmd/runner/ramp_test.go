@@ -0,0 +1,32 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRampSteps(t *testing.T) {
+	defaults := rampStep{Namespace: "default", DeploymentName: "benchmark"}
+
+	steps, err := parseRampSteps([]string{
+		"at=2m,build-id=v2",
+		"at=1m, build-id=v2, percentage=25",
+		"build-id=v1,deployment=other,n=tenant-a",
+	}, defaults)
+	require.NoError(t, err)
+	require.Equal(t, []rampStep{
+		{Namespace: "tenant-a", DeploymentName: "other", BuildID: "v1", Current: true},
+		{At: time.Minute, Namespace: "default", DeploymentName: "benchmark", BuildID: "v2", Percentage: 25},
+		{At: 2 * time.Minute, Namespace: "default", DeploymentName: "benchmark", BuildID: "v2", Current: true},
+	}, steps)
+
+	for _, spec := range []string{"at=-1s", "at=soon", "percentage=101", "percentage=x", "bogus=1", "build-id"} {
+		_, err := parseRampSteps([]string{spec}, defaults)
+		require.Error(t, err, spec)
+	}
+
+	_, err = parseRampSteps([]string{"build-id=v2"}, rampStep{Namespace: "default"})
+	require.ErrorContains(t, err, "needs a deployment name")
+}
@@ -0,0 +1,302 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/HdrHistogram/hdrhistogram-go"
+	commonpb "go.temporal.io/api/common/v1"
+	"go.temporal.io/api/workflowservice/v1"
+	"golang.org/x/time/rate"
+	"google.golang.org/protobuf/proto"
+)
+
+// Read operations the runner can generate.
+const (
+	// readHistory fetches the whole history of an execution.
+	readHistory = "history"
+	// readLongPoll follows the history of an open execution with waitNewEvent
+	// until it closes, as UIs and CLIs do when watching a workflow.
+	readLongPoll = "long-poll"
+	// readDescribe calls DescribeWorkflowExecution.
+	readDescribe = "describe"
+)
+
+var readOps = []string{readHistory, readLongPoll, readDescribe}
+
+const (
+	// recentExecutions is how many of the most recently started executions
+	// reads are spread across.
+	recentExecutions = 1000
+	// longPollTimeout bounds how long a long-poll read follows an execution.
+	// A read that reaches it has simply stopped following.
+	longPollTimeout = time.Minute
+)
+
+// parseReadOps parses a comma separated list of read operations.
+func parseReadOps(s string) ([]string, error) {
+	var ops []string
+	for _, op := range strings.Split(s, ",") {
+		op = strings.TrimSpace(op)
+		switch op {
+		case "":
+			continue
+		case readHistory, readLongPoll, readDescribe:
+			ops = append(ops, op)
+		default:
+			return nil, fmt.Errorf("unknown read operation %q", op)
+		}
+	}
+	if len(ops) == 0 {
+		return nil, fmt.Errorf("no read operations given")
+	}
+	return ops, nil
+}
+
+// execution identifies a workflow execution started by the runner.
+type execution struct {
+	WorkflowID string
+	RunID      string
+}
+
+// readSummary describes the reads of one operation against a target.
+type readSummary struct {
+	Calls      uint64         `json:"calls"`
+	Errors     uint64         `json:"errors"`
+	Rate       float64        `json:"rate"`
+	TotalBytes uint64         `json:"totalBytes"`
+	MeanBytes  float64        `json:"meanBytes"`
+	Latency    latencySummary `json:"latency"`
+}
+
+// readStats counts the reads of one operation.
+type readStats struct {
+	calls   atomic.Uint64
+	errors  atomic.Uint64
+	bytes   atomic.Uint64
+	latency *latencyHistogram
+}
+
+func (s *readStats) summary(elapsed time.Duration) readSummary {
+	calls := s.calls.Load()
+	summary := readSummary{
+		Calls:      calls,
+		Errors:     s.errors.Load(),
+		Rate:       float64(calls) / elapsed.Seconds(),
+		TotalBytes: s.bytes.Load(),
+		Latency:    s.latency.summary(),
+	}
+	if ok := calls - summary.Errors; ok > 0 {
+		summary.MeanBytes = float64(summary.TotalBytes) / float64(ok)
+	}
+	return summary
+}
+
+// readGenerator reads the histories and descriptions of executions a target's
+// load generator started, at a target rate, cycling through the configured
+// operations.
+type readGenerator struct {
+	target  target
+	clients *clusterClient
+	ops     []string
+	metrics *generatorMetrics
+	limiter *rate.Limiter
+	slots   chan struct{}
+	next    atomic.Uint64
+	// pollTimeout is longPollTimeout, or shorter in tests.
+	pollTimeout time.Duration
+
+	mu     sync.Mutex
+	recent []execution
+	oldest int
+	open   map[execution]bool
+
+	stats map[string]*readStats
+}
+
+func newReadGenerator(t target, cc *clusterClient, ops []string, concurrency int, m *generatorMetrics) *readGenerator {
+	r := &readGenerator{
+		target:  t,
+		clients: cc,
+		ops:     ops,
+		metrics: m,
+		limiter: rate.NewLimiter(rate.Limit(t.ReadRate), 1),
+		slots:   make(chan struct{}, concurrency),
+		open:    make(map[execution]bool),
+		stats:   make(map[string]*readStats),
+
+		pollTimeout: longPollTimeout,
+	}
+	for _, op := range ops {
+		r.stats[op] = &readStats{latency: newLatencyHistogram()}
+	}
+	return r
+}
+
+// started makes an execution available to read.
+func (r *readGenerator) started(e execution, open bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(r.recent) < recentExecutions {
+		r.recent = append(r.recent, e)
+	} else {
+		r.recent[r.oldest] = e
+		r.oldest = (r.oldest + 1) % recentExecutions
+	}
+	if open {
+		r.open[e] = true
+	}
+}
+
+func (r *readGenerator) closed(e execution) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.open, e)
+}
+
+// pick chooses an execution for op: an open one to long-poll if there is one,
+// otherwise a recently started one.
+func (r *readGenerator) pick(op string) (execution, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if op == readLongPoll {
+		for e := range r.open {
+			return e, true
+		}
+	}
+	if len(r.recent) == 0 {
+		return execution{}, false
+	}
+	return r.recent[rand.IntN(len(r.recent))], true
+}
+
+// run reads until ctx is done. Reads still in flight are abandoned.
+func (r *readGenerator) run(ctx context.Context) {
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	for {
+		if err := r.limiter.Wait(ctx); err != nil {
+			return
+		}
+		select {
+		case r.slots <- struct{}{}:
+		case <-ctx.Done():
+			return
+		}
+
+		op := r.ops[int(r.next.Add(1)-1)%len(r.ops)]
+		e, ok := r.pick(op)
+		if !ok {
+			<-r.slots
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-r.slots }()
+
+			r.read(ctx, op, e)
+		}()
+	}
+}
+
+func (r *readGenerator) read(ctx context.Context, op string, e execution) {
+	start := time.Now()
+
+	var bytes int
+	var err error
+	switch op {
+	case readHistory:
+		bytes, err = r.fetchHistory(ctx, e, false)
+	case readLongPoll:
+		pollCtx, cancel := context.WithTimeout(ctx, r.pollTimeout)
+		defer cancel()
+		bytes, err = r.fetchHistory(pollCtx, e, true)
+		if err != nil && ctx.Err() == nil && errors.Is(pollCtx.Err(), context.DeadlineExceeded) {
+			err = nil
+		}
+	case readDescribe:
+		var resp *workflowservice.DescribeWorkflowExecutionResponse
+		resp, err = r.clients.Client().DescribeWorkflowExecution(ctx, e.WorkflowID, e.RunID)
+		bytes = proto.Size(resp)
+	}
+	latency := time.Since(start)
+
+	// Reads cut short by the end of the run aren't counted.
+	if ctx.Err() != nil {
+		return
+	}
+
+	s := r.stats[op]
+	s.calls.Add(1)
+	if err != nil {
+		fmt.Fprintf(messages, "[%s] %s read failed: %v\n", r.target.Label, op, err)
+		s.errors.Add(1)
+		r.metrics.readFailed(op, err)
+		return
+	}
+	s.bytes.Add(uint64(bytes))
+	r.metrics.read(op, bytes)
+
+	// A long-poll lasts as long as the execution stays open, which says
+	// nothing about how fast reads are served.
+	if op != readLongPoll {
+		s.latency.record(latency)
+		r.metrics.readLatency(op, latency)
+	}
+}
+
+// fetchHistory pages through an execution's history, returning the size of
+// the responses. With wait, pages are long-polled for new events until the
+// execution closes.
+func (r *readGenerator) fetchHistory(ctx context.Context, e execution, wait bool) (int, error) {
+	svc := r.clients.Client().WorkflowService()
+
+	var bytes int
+	var token []byte
+	for {
+		resp, err := svc.GetWorkflowExecutionHistory(ctx, &workflowservice.GetWorkflowExecutionHistoryRequest{
+			Namespace:     r.target.Namespace,
+			Execution:     &commonpb.WorkflowExecution{WorkflowId: e.WorkflowID, RunId: e.RunID},
+			NextPageToken: token,
+			WaitNewEvent:  wait,
+		})
+		if err != nil {
+			return bytes, err
+		}
+		bytes += proto.Size(resp)
+
+		token = resp.GetNextPageToken()
+		if len(token) == 0 {
+			return bytes, nil
+		}
+	}
+}
+
+func (r *readGenerator) summary(elapsed time.Duration) map[string]readSummary {
+	summaries := make(map[string]readSummary, len(r.stats))
+	for op, s := range r.stats {
+		summaries[op] = s.summary(elapsed)
+	}
+	return summaries
+}
+
+// snapshots returns the latency histogram of each operation.
+func (r *readGenerator) snapshots() map[string]*hdrhistogram.Snapshot {
+	snapshots := make(map[string]*hdrhistogram.Snapshot, len(r.stats))
+	for op, s := range r.stats {
+		snapshots[op] = s.latency.snapshot()
+	}
+	return snapshots
+}
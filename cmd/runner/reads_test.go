@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/uber-go/tally/v4"
+	"go.temporal.io/api/workflowservice/v1"
+	"go.temporal.io/sdk/client"
+	"google.golang.org/grpc"
+)
+
+// historyClient serves histories of open executions: a long-poll for new
+// events waits until its context is done.
+type historyClient struct {
+	client.Client
+}
+
+func (c *historyClient) WorkflowService() workflowservice.WorkflowServiceClient {
+	return &historyService{}
+}
+
+type historyService struct {
+	workflowservice.WorkflowServiceClient
+}
+
+func (s *historyService) GetWorkflowExecutionHistory(ctx context.Context, req *workflowservice.GetWorkflowExecutionHistoryRequest, _ ...grpc.CallOption) (*workflowservice.GetWorkflowExecutionHistoryResponse, error) {
+	if req.GetWaitNewEvent() {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	return &workflowservice.GetWorkflowExecutionHistoryResponse{}, nil
+}
+
+func TestReadLongPollTimeout(t *testing.T) {
+	tgt := target{Label: "default/benchmark", Namespace: "default", ReadRate: 1}
+	clients := newClusterClient("default", []*cluster{{Name: "a", client: &historyClient{}}})
+	r := newReadGenerator(tgt, clients, []string{readHistory, readLongPoll}, 1, newGeneratorMetrics(tally.NoopScope, tgt, workload{}))
+	r.pollTimeout = 10 * time.Millisecond
+
+	e := execution{WorkflowID: "wf", RunID: "run"}
+	r.read(context.Background(), readHistory, e)
+	r.read(context.Background(), readLongPoll, e)
+
+	// A long-poll that stops following an open execution hasn't failed, and
+	// how long it followed isn't read latency.
+	summary := r.summary(time.Second)
+	require.Equal(t, uint64(1), summary[readLongPoll].Calls)
+	require.Zero(t, summary[readLongPoll].Errors)
+	require.Zero(t, summary[readLongPoll].Latency.Count)
+	require.Equal(t, int64(1), summary[readHistory].Latency.Count)
+
+	// Reads cut short by the end of the run aren't counted.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	r.read(ctx, readLongPoll, e)
+	require.Equal(t, uint64(1), r.summary(time.Second)[readLongPoll].Calls)
+}
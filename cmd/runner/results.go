@@ -0,0 +1,45 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+)
+
+// runResults is the summary of a run written to the results file.
+type runResults struct {
+	Duration string          `json:"duration"`
+	Targets  []targetResults `json:"targets"`
+	Ramps    []rampEvent     `json:"ramps,omitempty"`
+}
+
+// targetResults summarises the load generated against a single target.
+type targetResults struct {
+	Label               string                      `json:"label"`
+	WorkflowType        string                      `json:"workflowType"`
+	Namespace           string                      `json:"namespace"`
+	TaskQueue           string                      `json:"taskQueue"`
+	ConcurrentWorkflows int                         `json:"concurrentWorkflows"`
+	TargetRate          float64                     `json:"targetRate,omitempty"`
+	Started             uint64                      `json:"started"`
+	StartFailed         uint64                      `json:"startFailed"`
+	Completed           uint64                      `json:"completed"`
+	Failed              uint64                      `json:"failed"`
+	Rate                float64                     `json:"rate"`
+	Latency             latencySummary              `json:"latency"`
+	CorrectedLatency    latencySummary              `json:"correctedLatency"`
+	History             *historySummary             `json:"history,omitempty"`
+	TargetReadRate      float64                     `json:"targetReadRate,omitempty"`
+	Reads               map[string]readSummary      `json:"reads,omitempty"`
+	TaskQueueStats      map[string]taskQueueSummary `json:"taskQueueStats,omitempty"`
+	Errors              map[string]uint64           `json:"errors,omitempty"`
+	Outages             []outageWindow              `json:"outages,omitempty"`
+	Failovers           []failoverImpact            `json:"failovers,omitempty"`
+}
+
+func writeResults(path string, r runResults) error {
+	b, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(b, '\n'), 0644)
+}
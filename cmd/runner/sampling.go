@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"math/rand/v2"
+	"sync"
+
+	enumspb "go.temporal.io/api/enums/v1"
+	"go.temporal.io/sdk/client"
+	"google.golang.org/protobuf/proto"
+)
+
+// historySummary describes the workflow histories of the sampled executions
+// of a target.
+type historySummary struct {
+	Sampled    int64   `json:"sampled"`
+	MeanEvents float64 `json:"meanEvents"`
+	MaxEvents  int64   `json:"maxEvents"`
+	MeanBytes  float64 `json:"meanBytes"`
+	MaxBytes   int64   `json:"maxBytes"`
+	// EventTypes is the mean number of each type of event per execution.
+	EventTypes map[string]float64 `json:"eventTypes"`
+	// Dropped is how many executions chosen for sampling were skipped
+	// because maxHistoryFetches were already in progress.
+	Dropped int64 `json:"dropped,omitempty"`
+}
+
+// maxHistoryFetches bounds the histories fetched at once for a target, so that
+// fetches can't pile up at high rates and sample fractions.
+const maxHistoryFetches = 10
+
+// historySampler fetches the history of a fraction of completed executions
+// and keeps totals of their size and shape.
+type historySampler struct {
+	fraction float64
+	fetches  chan struct{}
+
+	mu         sync.Mutex
+	dropped    int64
+	sampled    int64
+	events     int64
+	maxEvents  int64
+	bytes      int64
+	maxBytes   int64
+	eventTypes map[string]int64
+}
+
+func newHistorySampler(fraction float64) *historySampler {
+	return &historySampler{
+		fraction:   fraction,
+		fetches:    make(chan struct{}, maxHistoryFetches),
+		eventTypes: make(map[string]int64),
+	}
+}
+
+// sample reports whether the next completed execution should be sampled. If
+// so, it reserves one of the maxHistoryFetches, which record releases; if
+// they are all in use, the execution is counted as dropped instead.
+func (s *historySampler) sample() bool {
+	if s.fraction <= 0 || rand.Float64() >= s.fraction {
+		return false
+	}
+	select {
+	case s.fetches <- struct{}{}:
+		return true
+	default:
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		s.dropped++
+		return false
+	}
+}
+
+// record fetches the history of a completed execution, which sample chose,
+// and adds it to the totals.
+func (s *historySampler) record(ctx context.Context, c client.Client, workflowID, runID string) error {
+	defer func() { <-s.fetches }()
+
+	var events, bytes int64
+	eventTypes := make(map[string]int64)
+
+	iter := c.GetWorkflowHistory(ctx, workflowID, runID, false, enumspb.HISTORY_EVENT_FILTER_TYPE_ALL_EVENT)
+	for iter.HasNext() {
+		event, err := iter.Next()
+		if err != nil {
+			return err
+		}
+		events++
+		bytes += int64(proto.Size(event))
+		eventTypes[event.GetEventType().String()]++
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sampled++
+	s.events += events
+	s.maxEvents = max(s.maxEvents, events)
+	s.bytes += bytes
+	s.maxBytes = max(s.maxBytes, bytes)
+	for t, n := range eventTypes {
+		s.eventTypes[t] += n
+	}
+	return nil
+}
+
+func (s *historySampler) summary() *historySummary {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.sampled == 0 && s.dropped == 0 {
+		return nil
+	}
+
+	summary := &historySummary{
+		Sampled:    s.sampled,
+		MaxEvents:  s.maxEvents,
+		MaxBytes:   s.maxBytes,
+		EventTypes: make(map[string]float64, len(s.eventTypes)),
+		Dropped:    s.dropped,
+	}
+	if s.sampled > 0 {
+		summary.MeanEvents = float64(s.events) / float64(s.sampled)
+		summary.MeanBytes = float64(s.bytes) / float64(s.sampled)
+	}
+	for t, n := range s.eventTypes {
+		summary.EventTypes[t] = float64(n) / float64(s.sampled)
+	}
+	return summary
+}
+
+// mergeHistorySummaries combines summaries from several runners, weighting
+// each by the number of executions it sampled.
+func mergeHistorySummaries(summaries []*historySummary) *historySummary {
+	var merged *historySummary
+	for _, s := range summaries {
+		if s == nil || (s.Sampled == 0 && s.Dropped == 0) {
+			continue
+		}
+		if merged == nil {
+			merged = &historySummary{EventTypes: make(map[string]float64)}
+		}
+		merged.Dropped += s.Dropped
+		if s.Sampled == 0 {
+			continue
+		}
+
+		total := merged.Sampled + s.Sampled
+		weight := func(a, b float64) float64 {
+			return (a*float64(merged.Sampled) + b*float64(s.Sampled)) / float64(total)
+		}
+
+		merged.MeanEvents = weight(merged.MeanEvents, s.MeanEvents)
+		merged.MeanBytes = weight(merged.MeanBytes, s.MeanBytes)
+		for t := range s.EventTypes {
+			if _, ok := merged.EventTypes[t]; !ok {
+				merged.EventTypes[t] = 0
+			}
+		}
+		for t, mean := range merged.EventTypes {
+			merged.EventTypes[t] = weight(mean, s.EventTypes[t])
+		}
+		merged.MaxEvents = max(merged.MaxEvents, s.MaxEvents)
+		merged.MaxBytes = max(merged.MaxBytes, s.MaxBytes)
+		merged.Sampled = total
+	}
+	return merged
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	enumspb "go.temporal.io/api/enums/v1"
+	historypb "go.temporal.io/api/history/v1"
+	"go.temporal.io/sdk/client"
+)
+
+func TestMergeHistorySummaries(t *testing.T) {
+	merged := mergeHistorySummaries([]*historySummary{
+		{Sampled: 1, MeanEvents: 10, MaxEvents: 10, MeanBytes: 1000, MaxBytes: 1000, EventTypes: map[string]float64{"ActivityTaskScheduled": 1}},
+		nil,
+		{Sampled: 3, MeanEvents: 20, MaxEvents: 30, MeanBytes: 2000, MaxBytes: 2500, EventTypes: map[string]float64{"TimerStarted": 2}},
+		{Dropped: 2},
+	})
+
+	require.Equal(t, int64(4), merged.Sampled)
+	require.Equal(t, 17.5, merged.MeanEvents)
+	require.Equal(t, int64(30), merged.MaxEvents)
+	require.Equal(t, 1750.0, merged.MeanBytes)
+	require.Equal(t, int64(2500), merged.MaxBytes)
+	require.Equal(t, map[string]float64{"ActivityTaskScheduled": 0.25, "TimerStarted": 1.5}, merged.EventTypes)
+	require.Equal(t, int64(2), merged.Dropped)
+
+	require.Nil(t, mergeHistorySummaries([]*historySummary{nil}))
+}
+
+// emptyHistoryClient returns an empty history for every execution.
+type emptyHistoryClient struct {
+	client.Client
+}
+
+func (emptyHistoryClient) GetWorkflowHistory(context.Context, string, string, bool, enumspb.HistoryEventFilterType) client.HistoryEventIterator {
+	return emptyHistory{}
+}
+
+type emptyHistory struct{}
+
+func (emptyHistory) HasNext() bool { return false }
+
+func (emptyHistory) Next() (*historypb.HistoryEvent, error) { return nil, nil }
+
+func TestHistorySamplerBounded(t *testing.T) {
+	s := newHistorySampler(1)
+	for range maxHistoryFetches {
+		require.True(t, s.sample())
+	}
+
+	// With every fetch in progress, further samples are dropped.
+	require.False(t, s.sample())
+	require.Equal(t, int64(1), s.summary().Dropped)
+
+	require.NoError(t, s.record(context.Background(), emptyHistoryClient{}, "wf", "run"))
+	require.True(t, s.sample())
+	require.Equal(t, int64(1), s.summary().Sampled)
+}
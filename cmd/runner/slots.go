@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"sync"
+)
+
+// slots is a counting semaphore whose size can be changed, and which can be
+// paused, while executions are in flight.
+type slots struct {
+	mu     sync.Mutex
+	limit  int
+	used   int
+	paused bool
+	// wake is closed and replaced whenever a slot may have become available.
+	wake chan struct{}
+}
+
+func newSlots(limit int) *slots {
+	return &slots{limit: limit, wake: make(chan struct{})}
+}
+
+// acquire blocks until a slot is free and the slots are not paused.
+func (s *slots) acquire(ctx context.Context) error {
+	for {
+		s.mu.Lock()
+		if !s.paused && s.used < s.limit {
+			s.used++
+			s.mu.Unlock()
+			return nil
+		}
+		wake := s.wake
+		s.mu.Unlock()
+
+		select {
+		case <-wake:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (s *slots) release() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.used--
+	s.notify()
+}
+
+// setLimit changes the number of slots. Lowering it doesn't interrupt
+// executions already holding a slot.
+func (s *slots) setLimit(limit int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.limit = limit
+	s.notify()
+}
+
+// setPaused pauses or resumes the slots.
+func (s *slots) setPaused(paused bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.paused = paused
+	s.notify()
+}
+
+func (s *slots) state() (limit, used int, paused bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.limit, s.used, s.paused
+}
+
+func (s *slots) notify() {
+	close(s.wake)
+	s.wake = make(chan struct{})
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSlots(t *testing.T) {
+	ctx := context.Background()
+	s := newSlots(1)
+	acquire := s.acquire
+
+	require.NoError(t, acquire(ctx))
+
+	// Full: acquire blocks until the context is done.
+	timeoutCtx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	require.Error(t, acquire(timeoutCtx))
+
+	// Raising the limit wakes a waiting acquire.
+	acquired := make(chan error)
+	go func() { acquired <- acquire(ctx) }()
+	s.setLimit(2)
+	require.NoError(t, <-acquired)
+
+	// Paused: nothing can be acquired even with free slots.
+	s.release()
+	s.setPaused(true)
+	go func() { acquired <- acquire(ctx) }()
+	select {
+	case <-acquired:
+		t.Fatal("acquired while paused")
+	case <-time.After(10 * time.Millisecond):
+	}
+	s.setPaused(false)
+	require.NoError(t, <-acquired)
+
+	limit, used, paused := s.state()
+	require.Equal(t, 2, limit)
+	require.Equal(t, 2, used)
+	require.False(t, paused)
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// target is a namespace and task queue the runner generates load against.
+type target struct {
+	Label       string
+	Namespace   string
+	TaskQueue   string
+	Concurrency int
+	// Rate is the target number of workflow starts per second. Zero means
+	// closed-loop: a new workflow is started each time one completes.
+	Rate float64
+	// ReadRate is the target number of history and describe reads per
+	// second. Zero means no reads.
+	ReadRate float64
+}
+
+// targetList collects repeated -target flags.
+type targetList []string
+
+func (l *targetList) String() string {
+	return strings.Join(*l, ";")
+}
+
+func (l *targetList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// parseTarget parses a target spec of comma-separated key=value pairs, e.g.
+// "n=tenant-a,tq=benchmark,c=20,r=50,rr=10,label=a". Keys not given are taken from
+// defaults.
+func parseTarget(spec string, defaults target) (target, error) {
+	t := defaults
+	t.Label = ""
+
+	for _, kv := range strings.Split(spec, ",") {
+		kv = strings.TrimSpace(kv)
+		if kv == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(kv, "=")
+		if !ok {
+			return t, fmt.Errorf("invalid target option %q, expected key=value", kv)
+		}
+
+		switch key {
+		case "n", "namespace":
+			t.Namespace = value
+		case "tq", "task-queue":
+			t.TaskQueue = value
+		case "c", "concurrency":
+			c, err := strconv.Atoi(value)
+			if err != nil || c <= 0 {
+				return t, fmt.Errorf("invalid concurrency %q", value)
+			}
+			t.Concurrency = c
+		case "r", "rate":
+			r, err := strconv.ParseFloat(value, 64)
+			if err != nil || r < 0 {
+				return t, fmt.Errorf("invalid rate %q", value)
+			}
+			t.Rate = r
+		case "rr", "read-rate":
+			r, err := strconv.ParseFloat(value, 64)
+			if err != nil || r < 0 {
+				return t, fmt.Errorf("invalid read rate %q", value)
+			}
+			t.ReadRate = r
+		case "label":
+			t.Label = value
+		default:
+			return t, fmt.Errorf("unknown target option %q", key)
+		}
+	}
+
+	if t.Label == "" {
+		t.Label = t.Namespace + "/" + t.TaskQueue
+	}
+
+	return t, nil
+}
+
+// parseTargets parses each spec, falling back to a single default target when
+// none are given.
+func parseTargets(specs []string, defaults target) ([]target, error) {
+	if len(specs) == 0 {
+		defaults.Label = defaults.Namespace + "/" + defaults.TaskQueue
+		return []target{defaults}, nil
+	}
+
+	var targets []target
+	seen := make(map[string]bool)
+	for _, spec := range specs {
+		t, err := parseTarget(spec, defaults)
+		if err != nil {
+			return nil, err
+		}
+		if seen[t.Label] {
+			return nil, fmt.Errorf("duplicate target %q", t.Label)
+		}
+		seen[t.Label] = true
+		targets = append(targets, t)
+	}
+
+	return targets, nil
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTargets(t *testing.T) {
+	defaults := target{Namespace: "default", TaskQueue: "benchmark", Concurrency: 10}
+
+	targets, err := parseTargets(nil, defaults)
+	require.NoError(t, err)
+	require.Equal(t, []target{{Label: "default/benchmark", Namespace: "default", TaskQueue: "benchmark", Concurrency: 10}}, targets)
+
+	targets, err = parseTargets([]string{
+		"n=tenant-a,c=5",
+		"namespace=tenant-b, task-queue=other, rate=2.5, rr=4, label=b",
+	}, defaults)
+	require.NoError(t, err)
+	require.Equal(t, []target{
+		{Label: "tenant-a/benchmark", Namespace: "tenant-a", TaskQueue: "benchmark", Concurrency: 5},
+		{Label: "b", Namespace: "tenant-b", TaskQueue: "other", Concurrency: 10, Rate: 2.5, ReadRate: 4},
+	}, targets)
+
+	for _, spec := range []string{"c=0", "r=-1", "rr=x", "bogus=1", "tq", "c=ten"} {
+		_, err := parseTargets([]string{spec}, defaults)
+		require.Error(t, err, spec)
+	}
+
+	_, err = parseTargets([]string{"n=a", "n=a"}, defaults)
+	require.ErrorContains(t, err, "duplicate target")
+}
+
+func TestParseReadOps(t *testing.T) {
+	ops, err := parseReadOps("history, describe")
+	require.NoError(t, err)
+	require.Equal(t, []string{readHistory, readDescribe}, ops)
+
+	_, err = parseReadOps("history,list")
+	require.ErrorContains(t, err, "unknown read operation")
+	_, err = parseReadOps("")
+	require.Error(t, err)
+}
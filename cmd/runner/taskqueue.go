@@ -0,0 +1,181 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	enumspb "go.temporal.io/api/enums/v1"
+	taskqueuepb "go.temporal.io/api/taskqueue/v1"
+	"go.temporal.io/api/workflowservice/v1"
+)
+
+// taskQueueTypes are the types of task on a task queue that are described, in
+// the order they are reported.
+var taskQueueTypes = []string{"workflow", "activity"}
+
+var taskQueueTypeValues = map[string]enumspb.TaskQueueType{
+	"workflow": enumspb.TASK_QUEUE_TYPE_WORKFLOW,
+	"activity": enumspb.TASK_QUEUE_TYPE_ACTIVITY,
+}
+
+// taskQueueStats is what DescribeTaskQueue last reported for one type of task
+// on a target's task queue. The server approximates the backlog and rates.
+type taskQueueStats struct {
+	Pollers      int     `json:"pollers"`
+	BacklogCount int64   `json:"backlogCount"`
+	BacklogAge   string  `json:"backlogAge"`
+	AddRate      float64 `json:"addRate"`
+	DispatchRate float64 `json:"dispatchRate"`
+}
+
+// taskQueueSummary is the extremes of the stats reported for one type of task
+// over a run. A backlog that keeps growing while pollers are steady points at
+// a shortage of workers rather than a server limit.
+type taskQueueSummary struct {
+	Samples         int     `json:"samples"`
+	MinPollers      int     `json:"minPollers"`
+	MaxPollers      int     `json:"maxPollers"`
+	MaxBacklogCount int64   `json:"maxBacklogCount"`
+	MaxBacklogAge   string  `json:"maxBacklogAge"`
+	MaxAddRate      float64 `json:"maxAddRate"`
+	MaxDispatchRate float64 `json:"maxDispatchRate"`
+}
+
+// merge combines the summaries of the same task queue from several runners.
+func (s taskQueueSummary) merge(o taskQueueSummary) taskQueueSummary {
+	if s.Samples == 0 {
+		return o
+	}
+	if o.Samples == 0 {
+		return s
+	}
+	s.Samples += o.Samples
+	s.MinPollers = min(s.MinPollers, o.MinPollers)
+	s.MaxPollers = max(s.MaxPollers, o.MaxPollers)
+	s.MaxBacklogCount = max(s.MaxBacklogCount, o.MaxBacklogCount)
+	s.MaxAddRate = max(s.MaxAddRate, o.MaxAddRate)
+	s.MaxDispatchRate = max(s.MaxDispatchRate, o.MaxDispatchRate)
+	a, _ := time.ParseDuration(s.MaxBacklogAge)
+	b, _ := time.ParseDuration(o.MaxBacklogAge)
+	s.MaxBacklogAge = max(a, b).String()
+	return s
+}
+
+// taskQueueMonitor periodically describes a target's task queue, keeping the
+// latest stats and their extremes over the run.
+type taskQueueMonitor struct {
+	target  target
+	clients *clusterClient
+	metrics *generatorMetrics
+
+	mu        sync.Mutex
+	latest    map[string]taskQueueStats
+	summaries map[string]taskQueueSummary
+}
+
+func newTaskQueueMonitor(t target, cc *clusterClient, m *generatorMetrics) *taskQueueMonitor {
+	return &taskQueueMonitor{
+		target:    t,
+		clients:   cc,
+		metrics:   m,
+		latest:    make(map[string]taskQueueStats),
+		summaries: make(map[string]taskQueueSummary),
+	}
+}
+
+// run describes the task queue every interval until ctx is done. The first
+// description is an interval into the run, so that pollers have had time to
+// arrive.
+func (m *taskQueueMonitor) run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+
+		for _, tqType := range taskQueueTypes {
+			if err := m.describe(ctx, tqType); err != nil && ctx.Err() == nil {
+				fmt.Fprintf(messages, "[%s] Unable to describe %s task queue: %v\n", m.target.Label, tqType, err)
+			}
+		}
+	}
+}
+
+func (m *taskQueueMonitor) describe(ctx context.Context, tqType string) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	resp, err := m.clients.Client().WorkflowService().DescribeTaskQueue(ctx, &workflowservice.DescribeTaskQueueRequest{
+		Namespace:     m.target.Namespace,
+		TaskQueue:     &taskqueuepb.TaskQueue{Name: m.target.TaskQueue, Kind: enumspb.TASK_QUEUE_KIND_NORMAL},
+		TaskQueueType: taskQueueTypeValues[tqType],
+		ReportStats:   true,
+	})
+	if err != nil {
+		return err
+	}
+
+	stats := resp.GetStats()
+	age := stats.GetApproximateBacklogAge().AsDuration().Round(time.Millisecond)
+	latest := taskQueueStats{
+		Pollers:      len(resp.GetPollers()),
+		BacklogCount: stats.GetApproximateBacklogCount(),
+		BacklogAge:   age.String(),
+		AddRate:      float64(stats.GetTasksAddRate()),
+		DispatchRate: float64(stats.GetTasksDispatchRate()),
+	}
+	m.metrics.taskQueue(tqType, latest, age)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.latest[tqType] = latest
+	m.summaries[tqType] = m.summaries[tqType].merge(taskQueueSummary{
+		Samples:         1,
+		MinPollers:      latest.Pollers,
+		MaxPollers:      latest.Pollers,
+		MaxBacklogCount: latest.BacklogCount,
+		MaxBacklogAge:   latest.BacklogAge,
+		MaxAddRate:      latest.AddRate,
+		MaxDispatchRate: latest.DispatchRate,
+	})
+	return nil
+}
+
+// stats returns the latest stats for each type of task described so far.
+func (m *taskQueueMonitor) stats() map[string]taskQueueStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stats := make(map[string]taskQueueStats, len(m.latest))
+	for tqType, s := range m.latest {
+		stats[tqType] = s
+	}
+	return stats
+}
+
+func (m *taskQueueMonitor) summary() map[string]taskQueueSummary {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if len(m.summaries) == 0 {
+		return nil
+	}
+	summaries := make(map[string]taskQueueSummary, len(m.summaries))
+	for tqType, s := range m.summaries {
+		summaries[tqType] = s
+	}
+	return summaries
+}
+
+// formatTaskQueueStats describes the latest stats of one type of task.
+func formatTaskQueueStats(s taskQueueStats) string {
+	return fmt.Sprintf("pollers %d backlog %d age %s add %.1f/s dispatch %.1f/s",
+		s.Pollers, s.BacklogCount, s.BacklogAge, s.AddRate, s.DispatchRate)
+}
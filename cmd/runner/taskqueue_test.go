@@ -0,0 +1,23 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTaskQueueSummaryMerge(t *testing.T) {
+	a := taskQueueSummary{Samples: 2, MinPollers: 2, MaxPollers: 4, MaxBacklogCount: 10, MaxBacklogAge: "1.5s", MaxAddRate: 100, MaxDispatchRate: 90}
+	b := taskQueueSummary{Samples: 3, MinPollers: 1, MaxPollers: 3, MaxBacklogCount: 40, MaxBacklogAge: "500ms", MaxAddRate: 80, MaxDispatchRate: 95}
+
+	require.Equal(t, a, taskQueueSummary{}.merge(a))
+	require.Equal(t, taskQueueSummary{
+		Samples:         5,
+		MinPollers:      1,
+		MaxPollers:      4,
+		MaxBacklogCount: 40,
+		MaxBacklogAge:   "1.5s",
+		MaxAddRate:      100,
+		MaxDispatchRate: 95,
+	}, a.merge(b))
+}
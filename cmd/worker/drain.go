@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/interceptor"
+)
+
+// drainTracker follows the activities executing on the process's workers, so
+// that a shutdown can report how many it let finish. It also holds whether the
+// process is ready, which is flipped before the workers are stopped.
+type drainTracker struct {
+	interceptor.WorkerInterceptorBase
+
+	metrics client.MetricsHandler
+	ready   atomic.Bool
+
+	mu           sync.Mutex
+	inFlight     int
+	draining     bool
+	drainStart   time.Time
+	drainedFrom  int
+	drainedCount int
+}
+
+// drainReport describes a shutdown.
+type drainReport struct {
+	// InFlight is the number of activities executing when the drain started.
+	InFlight int
+	// Completed is how many of them finished before the workers stopped.
+	Completed int
+	// Abandoned is how many were still executing, or were cancelled, when
+	// the worker stop timeout ran out.
+	Abandoned int
+	Duration  time.Duration
+}
+
+func newDrainTracker(metrics client.MetricsHandler) *drainTracker {
+	if metrics == nil {
+		metrics = client.MetricsNopHandler
+	}
+	return &drainTracker{metrics: metrics}
+}
+
+// setReady records whether the workers are running and not shutting down.
+func (d *drainTracker) setReady(ready bool) {
+	d.ready.Store(ready)
+	value := 0.0
+	if ready {
+		value = 1
+	}
+	d.metrics.Gauge("benchmark_worker_ready").Update(value)
+}
+
+func (d *drainTracker) isReady() bool {
+	return d.ready.Load()
+}
+
+// startDrain notes the activities in flight as the workers are stopped.
+func (d *drainTracker) startDrain() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.draining = true
+	d.drainStart = time.Now()
+	d.drainedFrom = d.inFlight
+}
+
+// report summarises the drain once the workers have stopped, and records it in
+// the drain metrics.
+func (d *drainTracker) report() drainReport {
+	d.mu.Lock()
+	r := drainReport{
+		InFlight:  d.drainedFrom,
+		Completed: d.drainedCount,
+		Abandoned: d.drainedFrom - d.drainedCount,
+		Duration:  time.Since(d.drainStart),
+	}
+	d.mu.Unlock()
+
+	d.metrics.Gauge("benchmark_worker_drain_in_flight").Update(float64(r.InFlight))
+	d.metrics.Gauge("benchmark_worker_drain_completed").Update(float64(r.Completed))
+	d.metrics.Gauge("benchmark_worker_drain_abandoned").Update(float64(r.Abandoned))
+	d.metrics.Gauge("benchmark_worker_drain_duration").Update(r.Duration.Seconds())
+	return r
+}
+
+// started records an activity starting, returning whether it started before
+// the drain.
+func (d *drainTracker) started() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.inFlight++
+	d.metrics.Gauge("benchmark_worker_activities_in_flight").Update(float64(d.inFlight))
+	return !d.draining
+}
+
+// finished records an activity finishing. An activity in flight when the drain
+// started completes it unless its context was cancelled by the worker stopping.
+func (d *drainTracker) finished(beforeDrain, cancelled bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.inFlight--
+	d.metrics.Gauge("benchmark_worker_activities_in_flight").Update(float64(d.inFlight))
+	if d.draining && beforeDrain && !cancelled {
+		d.drainedCount++
+	}
+}
+
+func (d *drainTracker) InterceptActivity(ctx context.Context, next interceptor.ActivityInboundInterceptor) interceptor.ActivityInboundInterceptor {
+	i := &drainActivityInterceptor{tracker: d}
+	i.Next = next
+	return i
+}
+
+type drainActivityInterceptor struct {
+	interceptor.ActivityInboundInterceptorBase
+	tracker *drainTracker
+}
+
+func (i *drainActivityInterceptor) ExecuteActivity(ctx context.Context, in *interceptor.ExecuteActivityInput) (interface{}, error) {
+	beforeDrain := i.tracker.started()
+	result, err := i.Next.ExecuteActivity(ctx, in)
+	i.tracker.finished(beforeDrain, ctx.Err() != nil)
+	return result, err
+}
+
+// logDrain logs the outcome of a drain.
+func logDrain(r drainReport) {
+	log.Printf("Drained workers in %s: %d activities in flight, %d completed, %d abandoned",
+		r.Duration.Round(time.Millisecond), r.InFlight, r.Completed, r.Abandoned)
+}
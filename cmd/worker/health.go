@@ -0,0 +1,287 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/temporalio/benchmark-workers/activities"
+	"github.com/temporalio/benchmark-workers/workflows"
+	enumspb "go.temporal.io/api/enums/v1"
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/temporal"
+)
+
+// Readiness checks that call the server run in the background every
+// readyCheckInterval, so that probes don't add load to the server, and each is
+// bounded by readyTimeout.
+const (
+	readyCheckInterval = 5 * time.Second
+	readyTimeout       = 2 * time.Second
+)
+
+// runningWorker is a worker the process has started, with the client it uses.
+type runningWorker struct {
+	spec   workerSpec
+	client client.Client
+}
+
+// healthServer serves the worker's health, readiness and debug endpoints.
+type healthServer struct {
+	identity string
+	drain    *drainTracker
+	info     debugInfo
+
+	mu      sync.Mutex
+	workers []runningWorker
+	// checks is the outcome of the last background readiness check, or nil
+	// before the first has run.
+	checks map[string]string
+	ready  bool
+}
+
+// debugInfo describes how the process is configured, for /debug/info.
+type debugInfo struct {
+	SDKVersion      string       `json:"sdkVersion"`
+	GoVersion       string       `json:"goVersion"`
+	Identity        string       `json:"identity"`
+	StartTime       time.Time    `json:"startTime"`
+	StickyCacheSize int          `json:"stickyCacheSize"`
+	Tuner           string       `json:"tuner"`
+	ShutdownDelay   string       `json:"shutdownDelay"`
+	Workers         []workerInfo `json:"workers"`
+}
+
+// workerInfo describes one of the process's workers.
+type workerInfo struct {
+	Namespace       string            `json:"namespace"`
+	TaskQueue       string            `json:"taskQueue"`
+	Role            string            `json:"role"`
+	WorkflowPollers string            `json:"workflowPollers"`
+	ActivityPollers string            `json:"activityPollers"`
+	NexusPollers    string            `json:"nexusPollers"`
+	Versioning      string            `json:"versioning"`
+	Options         map[string]string `json:"options"`
+	Workflows       []string          `json:"workflows"`
+	Activities      []string          `json:"activities"`
+}
+
+func newDebugInfo(identity string, workers []workerSpec, stickyCacheSize int, tuner tunerConfig, shutdownDelay time.Duration) debugInfo {
+	info := debugInfo{
+		SDKVersion:      temporal.SDKVersion,
+		GoVersion:       runtime.Version(),
+		Identity:        identity,
+		StartTime:       time.Now(),
+		StickyCacheSize: stickyCacheSize,
+		Tuner:           "none",
+		ShutdownDelay:   shutdownDelay.String(),
+	}
+	if tuner.Kind != "" {
+		info.Tuner = tuner.String()
+	}
+
+	for _, spec := range workers {
+		wi := workerInfo{
+			Namespace:       spec.Namespace,
+			TaskQueue:       spec.TaskQueue,
+			Role:            spec.Role,
+			WorkflowPollers: spec.WorkflowPollers.String(),
+			ActivityPollers: spec.ActivityPollers.String(),
+			NexusPollers:    spec.NexusPollers.String(),
+			Versioning:      spec.Versioning.String(),
+			Options:         make(map[string]string),
+			Workflows:       []string{},
+			Activities:      []string{},
+		}
+		for _, opt := range tunedOptions(spec.Options, stickyCacheSize) {
+			if opt.set {
+				wi.Options[opt.name] = fmt.Sprint(opt.value)
+			} else {
+				wi.Options[opt.name] = "SDK default"
+			}
+		}
+		if spec.Role != roleActivities {
+			wi.Workflows = workflows.Names
+		}
+		if spec.Role != roleWorkflows {
+			wi.Activities = activities.Names
+		}
+		info.Workers = append(info.Workers, wi)
+	}
+	return info
+}
+
+func newHealthServer(identity string, drain *drainTracker, info debugInfo) *healthServer {
+	return &healthServer{identity: identity, drain: drain, info: info}
+}
+
+// addWorker adds a started worker to those checked for readiness.
+func (h *healthServer) addWorker(spec workerSpec, c client.Client) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.workers = append(h.workers, runningWorker{spec: spec, client: c})
+}
+
+// readiness returns the outcome of each readiness check and whether they all
+// passed. The process is ready once its workers have started, until shutdown
+// begins, while the last background check found the server reachable and
+// each worker polling for the types of task it runs.
+func (h *healthServer) readiness() (map[string]string, bool) {
+	if !h.drain.isReady() {
+		return map[string]string{"workers": "not running"}, false
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	checks := map[string]string{"workers": "ok"}
+	if h.checks == nil {
+		checks["server"] = "not checked yet"
+		return checks, false
+	}
+	for name, outcome := range h.checks {
+		checks[name] = outcome
+	}
+	return checks, h.ready
+}
+
+// checkPeriodically runs the server readiness checks every readyCheckInterval
+// until ctx is done.
+func (h *healthServer) checkPeriodically(ctx context.Context) {
+	ticker := time.NewTicker(readyCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		h.check(ctx)
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// check checks that the server is reachable and has seen each started worker
+// polling its task queue, recording the outcome for readiness.
+func (h *healthServer) check(ctx context.Context) {
+	h.mu.Lock()
+	workers := append([]runningWorker(nil), h.workers...)
+	h.mu.Unlock()
+
+	if len(workers) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, readyTimeout)
+	defer cancel()
+
+	checks := make(map[string]string)
+	ready := true
+	record := func(name string, err error) {
+		if err != nil {
+			checks[name] = err.Error()
+			ready = false
+			return
+		}
+		checks[name] = "ok"
+	}
+
+	_, err := workers[0].client.CheckHealth(ctx, &client.CheckHealthRequest{})
+	record("client", err)
+	if err == nil {
+		for _, w := range workers {
+			var tqTypes []enumspb.TaskQueueType
+			if w.spec.Role != roleActivities {
+				tqTypes = append(tqTypes, enumspb.TASK_QUEUE_TYPE_WORKFLOW)
+			}
+			if w.spec.Role != roleWorkflows {
+				tqTypes = append(tqTypes, enumspb.TASK_QUEUE_TYPE_ACTIVITY)
+			}
+			for _, tqType := range tqTypes {
+				name := fmt.Sprintf("pollers %s/%s %s", w.spec.Namespace, w.spec.TaskQueue, tqTypeName(tqType))
+				record(name, h.polling(ctx, w, tqType))
+			}
+		}
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.checks, h.ready = checks, ready
+}
+
+// polling checks that the server has seen this process polling the worker's
+// task queue for one type of task.
+func (h *healthServer) polling(ctx context.Context, w runningWorker, tqType enumspb.TaskQueueType) error {
+	resp, err := w.client.DescribeTaskQueue(ctx, w.spec.TaskQueue, tqType)
+	if err != nil {
+		return err
+	}
+	for _, p := range resp.GetPollers() {
+		if p.GetIdentity() == h.identity {
+			return nil
+		}
+	}
+	return errors.New("no pollers")
+}
+
+func tqTypeName(tqType enumspb.TaskQueueType) string {
+	if tqType == enumspb.TASK_QUEUE_TYPE_WORKFLOW {
+		return "workflow"
+	}
+	return "activity"
+}
+
+// handler returns the health endpoints, serving metrics as well if given a
+// metrics handler.
+func (h *healthServer) handler(metrics http.Handler) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "ok")
+	})
+	mux.HandleFunc("GET /readyz", func(w http.ResponseWriter, r *http.Request) {
+		checks, ready := h.readiness()
+		status, code := "ready", http.StatusOK
+		if !ready {
+			status, code = "not ready", http.StatusServiceUnavailable
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(code)
+		json.NewEncoder(w).Encode(map[string]interface{}{"status": status, "checks": checks})
+	})
+	mux.HandleFunc("GET /debug/info", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, h.info)
+	})
+	if metrics != nil {
+		mux.Handle("GET /metrics", metrics)
+	}
+	return mux
+}
+
+// serve serves the health endpoints on addr until ctx is done.
+func (h *healthServer) serve(ctx context.Context, addr string, metrics http.Handler) {
+	server := &http.Server{Addr: addr, Handler: h.handler(metrics)}
+	go func() {
+		<-ctx.Done()
+		server.Shutdown(context.Background())
+	}()
+
+	go h.checkPeriodically(ctx)
+
+	log.Printf("Health endpoints listening on %s", addr)
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Fatalf("Health endpoints failed: %v", err)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	enumspb "go.temporal.io/api/enums/v1"
+	taskqueuepb "go.temporal.io/api/taskqueue/v1"
+	"go.temporal.io/api/workflowservice/v1"
+	"go.temporal.io/sdk/client"
+)
+
+// healthClient answers the readiness checks' calls, with pollers seen from
+// the given identities.
+type healthClient struct {
+	client.Client
+	healthErr  error
+	identities []string
+}
+
+func (c *healthClient) CheckHealth(context.Context, *client.CheckHealthRequest) (*client.CheckHealthResponse, error) {
+	return &client.CheckHealthResponse{}, c.healthErr
+}
+
+func (c *healthClient) DescribeTaskQueue(context.Context, string, enumspb.TaskQueueType) (*workflowservice.DescribeTaskQueueResponse, error) {
+	resp := &workflowservice.DescribeTaskQueueResponse{}
+	for _, identity := range c.identities {
+		resp.Pollers = append(resp.Pollers, &taskqueuepb.PollerInfo{Identity: identity})
+	}
+	return resp, nil
+}
+
+func TestReadiness(t *testing.T) {
+	drain := newDrainTracker(nil)
+	health := newHealthServer("1@host@", drain, debugInfo{})
+	c := &healthClient{identities: []string{"2@host@"}}
+	health.addWorker(workerSpec{Namespace: "default", TaskQueue: "benchmark", Role: roleActivities}, c)
+
+	checks, ready := health.readiness()
+	require.False(t, ready)
+	require.Equal(t, "not running", checks["workers"])
+
+	// Readiness waits for the first background check.
+	drain.setReady(true)
+	checks, ready = health.readiness()
+	require.False(t, ready)
+	require.Equal(t, "not checked yet", checks["server"])
+
+	health.check(context.Background())
+	checks, ready = health.readiness()
+	require.False(t, ready)
+	require.Equal(t, "no pollers", checks["pollers default/benchmark activity"])
+
+	c.identities = append(c.identities, "1@host@")
+	health.check(context.Background())
+	checks, ready = health.readiness()
+	require.True(t, ready)
+	require.Equal(t, map[string]string{"workers": "ok", "client": "ok", "pollers default/benchmark activity": "ok"}, checks)
+
+	// A lost connection shows on the next check.
+	c.healthErr = errors.New("connection refused")
+	health.check(context.Background())
+	checks, ready = health.readiness()
+	require.False(t, ready)
+	require.Equal(t, "connection refused", checks["client"])
+
+	drain.setReady(false)
+	_, ready = health.readiness()
+	require.False(t, ready)
+}
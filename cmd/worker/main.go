@@ -1,30 +1,72 @@
 package main
 
 import (
+	"context"
 	"crypto/tls"
 	"crypto/x509"
 	"flag"
 	"fmt"
 	"log"
+	"net/http"
 	"os"
 	"strconv"
+	"strings"
+	"sync"
+	"time"
 
-	"github.com/temporalio/benchmark-workers/activities"
-	"github.com/temporalio/benchmark-workers/workflows"
-	"github.com/uber-go/tally/v4/prometheus"
+	prom "github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/temporalio/benchmark-workers/internal/metrics"
+	"github.com/temporalio/benchmark-workers/internal/profile"
+	"github.com/temporalio/benchmark-workers/internal/telemetry"
+	"go.temporal.io/sdk/contrib/opentelemetry"
 	sdktally "go.temporal.io/sdk/contrib/tally"
 	"go.uber.org/automaxprocs/maxprocs"
 
-	"go.temporal.io/sdk/activity"
 	"go.temporal.io/sdk/client"
 	"go.temporal.io/sdk/worker"
-	"go.temporal.io/sdk/workflow"
 )
 
 var sNamespace = flag.String("n", "default", "namespace")
 var sTaskQueue = flag.String("tq", "benchmark", "task queue")
 var nMaxWorkflowPollers = flag.Int("wp", -1, "max concurrent workflow task pollers (-1 = use default, 0 = disable)")
 var nMaxActivityPollers = flag.Int("ap", -1, "max concurrent activity task pollers (-1 = use default, 0 = disable)")
+var sWorkflowPollers = flag.String("workflow-pollers", "", "workflow task poller mode: simple:N or autoscaling:min=M,initial=I,max=X")
+var sActivityPollers = flag.String("activity-pollers", "", "activity task poller mode: simple:N or autoscaling:min=M,initial=I,max=X")
+var sNexusPollers = flag.String("nexus-pollers", "", "nexus task poller mode: simple:N or autoscaling:min=M,initial=I,max=X")
+var nMaxActivities = flag.Int("max-concurrent-activities", 0, "max concurrent activity executions (0 = use default)")
+var nMaxWorkflowTasks = flag.Int("max-concurrent-workflow-tasks", 0, "max concurrent workflow task executions (0 = use default)")
+var nMaxLocalActivities = flag.Int("max-concurrent-local-activities", 0, "max concurrent local activity executions (0 = use default)")
+var fTaskQueueActivityRate = flag.Float64("task-queue-activities-per-second", 0, "activities per second across all workers on the task queue (0 = use default)")
+var fWorkerActivityRate = flag.Float64("worker-activities-per-second", 0, "activities per second for this worker (0 = use default)")
+var dStickyScheduleToStart = flag.Duration("sticky-schedule-to-start-timeout", 0, "time a workflow task waits on the sticky queue before moving to the normal queue (0 = use default)")
+var dWorkerStopTimeout = flag.Duration("worker-stop-timeout", 0, "time to wait for in-flight activities to finish on shutdown (0 = use default)")
+var dDeadlockDetection = flag.Duration("deadlock-detection-timeout", 0, "maximum time workflow code may run without yielding (0 = use default)")
+var bDisableEagerActivities = flag.Bool("disable-eager-activities", false, "disable eager activity execution")
+var nStickyCacheSize = flag.Int("sticky-cache-size", 0, "number of workflows cached for sticky execution (0 = use default)")
+var sTuner = flag.String("tuner", "", "worker tuner: fixed or resource (default: use max concurrent execution sizes)")
+var nWorkflowSlots = flag.Int("workflow-slots", 0, "fixed number of workflow task slots for the tuner (0 = use default)")
+var nActivitySlots = flag.Int("activity-slots", 0, "fixed number of activity slots for the tuner (0 = use default)")
+var nLocalActivitySlots = flag.Int("local-activity-slots", 0, "fixed number of local activity slots for the tuner (0 = use default)")
+var fTargetCPU = flag.Float64("target-cpu", 0.8, "CPU utilization the resource tuner stops issuing slots at")
+var fTargetMemory = flag.Float64("target-memory", 0.8, "memory utilization the resource tuner stops issuing slots at")
+var sRole = flag.String("role", roleAll, "worker role: all, workflows or activities")
+var sDeploymentName = flag.String("deployment-name", "", "worker deployment name, to version the worker")
+var sBuildID = flag.String("build-id", "", "build ID of the worker's deployment version")
+var sVersioningBehavior = flag.String("versioning-behavior", "", "default workflow versioning behavior: pinned or auto-upgrade (default auto-upgrade)")
+var sWorkflowVersioningBehavior = flag.String("workflow-versioning-behavior", "", "comma separated workflow:behavior versioning behaviors, e.g. DSL:pinned")
+var dShutdownDelay = flag.Duration("shutdown-delay", 0, "time between marking the worker not ready and stopping it on shutdown")
+var dFinalMetricsDelay = flag.Duration("final-metrics-delay", 0, "time to keep serving metrics for scraping after the workers have stopped on shutdown")
+var sHealth = flag.String("health", "", "address to serve /healthz, /readyz and /debug/info on")
+var sPprof = flag.String("pprof", "", "address to serve the net/http/pprof endpoints on")
+var sProfileDir = flag.String("profile-dir", "", "directory to write CPU, heap, mutex and block profiles and a runtime trace to")
+var dProfileAfter = flag.Duration("profile-after", 0, "time after startup to start writing profiles to -profile-dir")
+var dProfileDuration = flag.Duration("profile-duration", 30*time.Second, "time to profile for when writing profiles to -profile-dir")
+var workerSpecs workerList
+
+func init() {
+	flag.Var(&workerSpecs, "worker", "additional `tq=task-queue,role=workflows,...` worker to run in the process, with any of the worker flags as options (repeatable)")
+}
 
 // Track which flags were explicitly set
 var flagsSet = make(map[string]bool)
@@ -51,7 +93,51 @@ func getIntValue(flagName, envName string, flagValue, defaultValue int) int {
 	return defaultValue
 }
 
+func getBoolValue(flagName, envName string, flagValue, defaultValue bool) bool {
+	if flagsSet[flagName] {
+		return flagValue
+	}
+	if envValue := os.Getenv(envName); envValue != "" {
+		if parsed, err := strconv.ParseBool(envValue); err == nil {
+			return parsed
+		}
+	}
+	return defaultValue
+}
+
+func getFloatValue(flagName, envName string, flagValue, defaultValue float64) float64 {
+	if flagsSet[flagName] {
+		return flagValue
+	}
+	if envValue := os.Getenv(envName); envValue != "" {
+		if parsed, err := strconv.ParseFloat(envValue, 64); err == nil {
+			return parsed
+		}
+	}
+	return defaultValue
+}
+
+func getDurationValue(flagName, envName string, flagValue, defaultValue time.Duration) time.Duration {
+	if flagsSet[flagName] {
+		return flagValue
+	}
+	if envValue := os.Getenv(envName); envValue != "" {
+		if parsed, err := time.ParseDuration(envValue); err == nil {
+			return parsed
+		}
+	}
+	return defaultValue
+}
+
 func main() {
+	if err := run(); err != nil {
+		log.Fatalf("Worker failed: %v", err)
+	}
+}
+
+// run runs the workers until the process is interrupted or one of them fails,
+// shutting down the same way in either case, and returns the failure.
+func run() error {
 	flag.Usage = func() {
 		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags]\n", os.Args[0])
 		flag.PrintDefaults()
@@ -60,6 +146,38 @@ func main() {
 		fmt.Fprintf(flag.CommandLine.Output(), "  TEMPORAL_TASK_QUEUE\n")
 		fmt.Fprintf(flag.CommandLine.Output(), "  TEMPORAL_MAX_WORKFLOW_TASK_POLLERS\n")
 		fmt.Fprintf(flag.CommandLine.Output(), "  TEMPORAL_MAX_ACTIVITY_TASK_POLLERS\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "  TEMPORAL_WORKFLOW_TASK_POLLERS\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "  TEMPORAL_ACTIVITY_TASK_POLLERS\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "  TEMPORAL_NEXUS_TASK_POLLERS\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "  TEMPORAL_MAX_CONCURRENT_ACTIVITY_EXECUTION_SIZE\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "  TEMPORAL_MAX_CONCURRENT_WORKFLOW_TASK_EXECUTION_SIZE\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "  TEMPORAL_MAX_CONCURRENT_LOCAL_ACTIVITY_EXECUTION_SIZE\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "  TEMPORAL_TASK_QUEUE_ACTIVITIES_PER_SECOND\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "  TEMPORAL_WORKER_ACTIVITIES_PER_SECOND\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "  TEMPORAL_STICKY_SCHEDULE_TO_START_TIMEOUT\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "  TEMPORAL_WORKER_STOP_TIMEOUT\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "  TEMPORAL_DEADLOCK_DETECTION_TIMEOUT\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "  TEMPORAL_DISABLE_EAGER_ACTIVITIES\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "  TEMPORAL_STICKY_CACHE_SIZE\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "  TEMPORAL_WORKER_TUNER\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "  TEMPORAL_WORKFLOW_TASK_SLOTS\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "  TEMPORAL_ACTIVITY_SLOTS\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "  TEMPORAL_LOCAL_ACTIVITY_SLOTS\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "  TEMPORAL_TUNER_TARGET_CPU\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "  TEMPORAL_TUNER_TARGET_MEMORY\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "  TEMPORAL_WORKER_ROLE\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "  TEMPORAL_DEPLOYMENT_NAME\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "  TEMPORAL_BUILD_ID\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "  TEMPORAL_VERSIONING_BEHAVIOR\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "  TEMPORAL_WORKFLOW_VERSIONING_BEHAVIOR\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "  TEMPORAL_WORKERS (semicolon separated list of -worker values)\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "  TEMPORAL_SHUTDOWN_DELAY\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "  TEMPORAL_FINAL_METRICS_DELAY\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "  HEALTH_ENDPOINT\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "  PPROF_ENDPOINT\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "  PROFILE_DIR\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "  PROFILE_AFTER\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "  PROFILE_DURATION\n")
 	}
 
 	flag.Parse()
@@ -79,7 +197,93 @@ func main() {
 	maxWorkflowPollers := getIntValue("wp", "TEMPORAL_MAX_WORKFLOW_TASK_POLLERS", *nMaxWorkflowPollers, -1)
 	maxActivityPollers := getIntValue("ap", "TEMPORAL_MAX_ACTIVITY_TASK_POLLERS", *nMaxActivityPollers, -1)
 
-	log.Printf("Creating worker for namespace: %s", namespace)
+	workflowPollers, err := resolvePollerSpec(getStringValue("workflow-pollers", "TEMPORAL_WORKFLOW_TASK_POLLERS", *sWorkflowPollers, ""), maxWorkflowPollers)
+	if err != nil {
+		log.Fatalf("Invalid workflow task pollers: %v", err)
+	}
+	activityPollers, err := resolvePollerSpec(getStringValue("activity-pollers", "TEMPORAL_ACTIVITY_TASK_POLLERS", *sActivityPollers, ""), maxActivityPollers)
+	if err != nil {
+		log.Fatalf("Invalid activity task pollers: %v", err)
+	}
+	nexusPollers, err := resolvePollerSpec(getStringValue("nexus-pollers", "TEMPORAL_NEXUS_TASK_POLLERS", *sNexusPollers, ""), -1)
+	if err != nil {
+		log.Fatalf("Invalid nexus task pollers: %v", err)
+	}
+	var options optionValues
+	stickyCacheSize := options.intValue("sticky-cache-size", "TEMPORAL_STICKY_CACHE_SIZE", *nStickyCacheSize, 0)
+
+	workerOptions := worker.Options{
+		MaxConcurrentActivityExecutionSize:      options.intValue("max-concurrent-activities", "TEMPORAL_MAX_CONCURRENT_ACTIVITY_EXECUTION_SIZE", *nMaxActivities, 0),
+		MaxConcurrentWorkflowTaskExecutionSize:  options.intValue("max-concurrent-workflow-tasks", "TEMPORAL_MAX_CONCURRENT_WORKFLOW_TASK_EXECUTION_SIZE", *nMaxWorkflowTasks, 0),
+		MaxConcurrentLocalActivityExecutionSize: options.intValue("max-concurrent-local-activities", "TEMPORAL_MAX_CONCURRENT_LOCAL_ACTIVITY_EXECUTION_SIZE", *nMaxLocalActivities, 0),
+		TaskQueueActivitiesPerSecond:            options.floatValue("task-queue-activities-per-second", "TEMPORAL_TASK_QUEUE_ACTIVITIES_PER_SECOND", *fTaskQueueActivityRate, 0),
+		WorkerActivitiesPerSecond:               options.floatValue("worker-activities-per-second", "TEMPORAL_WORKER_ACTIVITIES_PER_SECOND", *fWorkerActivityRate, 0),
+		StickyScheduleToStartTimeout:            options.durationValue("sticky-schedule-to-start-timeout", "TEMPORAL_STICKY_SCHEDULE_TO_START_TIMEOUT", *dStickyScheduleToStart, 0),
+		WorkerStopTimeout:                       options.durationValue("worker-stop-timeout", "TEMPORAL_WORKER_STOP_TIMEOUT", *dWorkerStopTimeout, 0),
+		DeadlockDetectionTimeout:                options.durationValue("deadlock-detection-timeout", "TEMPORAL_DEADLOCK_DETECTION_TIMEOUT", *dDeadlockDetection, 0),
+		DisableEagerActivities:                  options.boolValue("disable-eager-activities", "TEMPORAL_DISABLE_EAGER_ACTIVITIES", *bDisableEagerActivities, false),
+	}
+	shutdownDelay := options.durationValue("shutdown-delay", "TEMPORAL_SHUTDOWN_DELAY", *dShutdownDelay, 0)
+	finalMetricsDelay := options.durationValue("final-metrics-delay", "TEMPORAL_FINAL_METRICS_DELAY", *dFinalMetricsDelay, 0)
+	tuner := tunerConfig{
+		Kind:               getStringValue("tuner", "TEMPORAL_WORKER_TUNER", *sTuner, ""),
+		WorkflowSlots:      options.intValue("workflow-slots", "TEMPORAL_WORKFLOW_TASK_SLOTS", *nWorkflowSlots, 0),
+		ActivitySlots:      options.intValue("activity-slots", "TEMPORAL_ACTIVITY_SLOTS", *nActivitySlots, 0),
+		LocalActivitySlots: options.intValue("local-activity-slots", "TEMPORAL_LOCAL_ACTIVITY_SLOTS", *nLocalActivitySlots, 0),
+		TargetCPU:          options.floatValue("target-cpu", "TEMPORAL_TUNER_TARGET_CPU", *fTargetCPU, 0.8),
+		TargetMemory:       options.floatValue("target-memory", "TEMPORAL_TUNER_TARGET_MEMORY", *fTargetMemory, 0.8),
+	}
+	if err := options.err(); err != nil {
+		log.Fatalf("Invalid worker options: %v", err)
+	}
+	if shutdownDelay < 0 || finalMetricsDelay < 0 {
+		log.Fatalf("Invalid shutdown delay: must not be negative")
+	}
+
+	workflowBehaviors, err := parseWorkflowBehaviors(getStringValue("workflow-versioning-behavior", "TEMPORAL_WORKFLOW_VERSIONING_BEHAVIOR", *sWorkflowVersioningBehavior, ""))
+	if err != nil {
+		log.Fatalf("Invalid worker versioning: %v", err)
+	}
+	versioning := versioningConfig{
+		DeploymentName:    getStringValue("deployment-name", "TEMPORAL_DEPLOYMENT_NAME", *sDeploymentName, ""),
+		BuildID:           getStringValue("build-id", "TEMPORAL_BUILD_ID", *sBuildID, ""),
+		DefaultBehavior:   getStringValue("versioning-behavior", "TEMPORAL_VERSIONING_BEHAVIOR", *sVersioningBehavior, ""),
+		WorkflowBehaviors: workflowBehaviors,
+	}
+
+	specs := []string(workerSpecs)
+	if len(specs) == 0 && os.Getenv("TEMPORAL_WORKERS") != "" {
+		specs = strings.Split(os.Getenv("TEMPORAL_WORKERS"), ";")
+	}
+	workers, err := parseWorkerSpecs(specs, workerSpec{
+		Namespace:       namespace,
+		TaskQueue:       taskQueue,
+		Role:            getStringValue("role", "TEMPORAL_WORKER_ROLE", *sRole, roleAll),
+		Options:         workerOptions,
+		WorkflowPollers: workflowPollers,
+		ActivityPollers: activityPollers,
+		NexusPollers:    nexusPollers,
+		Versioning:      versioning,
+	})
+	if err != nil {
+		log.Fatalf("Invalid worker: %v", err)
+	}
+	for _, spec := range workers {
+		switch spec.Role {
+		case roleAll, roleWorkflows, roleActivities:
+		default:
+			log.Fatalf("Invalid worker: role must be %s, %s or %s", roleAll, roleWorkflows, roleActivities)
+		}
+		if err := validateWorkerOptions(spec.Options, stickyCacheSize); err != nil {
+			log.Fatalf("Invalid worker options: %v", err)
+		}
+		if err := tuner.validate(spec.Options); err != nil {
+			log.Fatalf("Invalid worker tuner: %v", err)
+		}
+		if err := spec.Versioning.validate(); err != nil {
+			log.Fatalf("Invalid worker versioning: %v", err)
+		}
+	}
 
 	clientOptions := client.Options{
 		HostPort:  os.Getenv("TEMPORAL_GRPC_ENDPOINT"),
@@ -119,11 +323,103 @@ func main() {
 		clientOptions.ConnectionOptions.TLS = &tlsConfig
 	}
 
-	if os.Getenv("PROMETHEUS_ENDPOINT") != "" {
-		clientOptions.MetricsHandler = sdktally.NewMetricsHandler(newPrometheusScope(prometheus.Configuration{
-			ListenAddress: os.Getenv("PROMETHEUS_ENDPOINT"),
-			TimerType:     "histogram",
-		}))
+	pprofAddr := getStringValue("pprof", "PPROF_ENDPOINT", *sPprof, "")
+	if pprofAddr != "" {
+		profile.EnableContentionProfiles()
+		pprofCtx, stopPprof := context.WithCancel(context.Background())
+		defer stopPprof()
+		go profile.Serve(pprofCtx, pprofAddr)
+	}
+	if profileDir := getStringValue("profile-dir", "PROFILE_DIR", *sProfileDir, ""); profileDir != "" {
+		window := profile.Window{
+			Dir:      profileDir,
+			After:    getDurationValue("profile-after", "PROFILE_AFTER", *dProfileAfter, 0),
+			Duration: getDurationValue("profile-duration", "PROFILE_DURATION", *dProfileDuration, 30*time.Second),
+		}
+		if err := window.Validate(); err != nil {
+			log.Fatalf("Invalid profile window: %v", err)
+		}
+		defer window.Start()()
+	}
+
+	healthAddr := getStringValue("health", "HEALTH_ENDPOINT", *sHealth, "")
+
+	pushgatewayURL := os.Getenv("PROMETHEUS_PUSHGATEWAY_URL")
+	remoteWriteURL := os.Getenv("PROMETHEUS_REMOTE_WRITE_URL")
+	var metricsHTTP http.Handler
+	// flushMetrics reports outstanding values to the registry, and scraped is
+	// whether the registry is served for Prometheus to scrape.
+	flushMetrics := func() {}
+	scraped := false
+	if os.Getenv("PROMETHEUS_ENDPOINT") != "" || pushgatewayURL != "" || remoteWriteURL != "" {
+		metricsConf, err := metrics.ConfigFromEnv()
+		if err != nil {
+			log.Fatalf("Invalid metrics configuration: %v", err)
+		}
+		registry := prom.NewRegistry()
+		if err := metrics.RegisterProcessMetrics(registry, "worker", metricsConf.Labels); err != nil {
+			log.Fatalf("Unable to register process metrics: %v", err)
+		}
+
+		// Metrics share the health endpoints' listener when given its address.
+		if metricsConf.ListenAddress != "" && metricsConf.ListenAddress == healthAddr {
+			metricsConf.ListenAddress = ""
+			metricsHTTP = promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+		}
+
+		scope, closer := metrics.NewPrometheusScope(metricsConf, registry)
+		clientOptions.MetricsHandler = sdktally.NewMetricsHandler(scope)
+		flushMetrics = func() { closer.Close() }
+		scraped = metricsConf.ListenAddress != "" || metricsHTTP != nil
+
+		stopPush, err := metrics.StartPushing(registry, closer, "benchmark-worker")
+		if err != nil {
+			log.Fatalf("Invalid metrics configuration: %v", err)
+		}
+		defer stopPush()
+	}
+
+	if os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") != "" {
+		shutdown, err := telemetry.NewOpenTelemetry(context.Background(), "benchmark-worker")
+		if err != nil {
+			log.Fatalf("Unable to set up OpenTelemetry: %v", err)
+		}
+		defer func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := shutdown(ctx); err != nil {
+				log.Printf("Unable to flush OpenTelemetry exporters: %v", err)
+			}
+		}()
+
+		tracingInterceptor, err := opentelemetry.NewTracingInterceptor(opentelemetry.TracerOptions{})
+		if err != nil {
+			log.Fatalf("Unable to create tracing interceptor: %v", err)
+		}
+		clientOptions.Interceptors = append(clientOptions.Interceptors, tracingInterceptor)
+
+		// Prometheus takes precedence for SDK metrics if both are configured.
+		if clientOptions.MetricsHandler == nil {
+			clientOptions.MetricsHandler = opentelemetry.NewMetricsHandler(opentelemetry.MetricsHandlerOptions{})
+		}
+
+		log.Printf("Exporting OpenTelemetry data to %s", os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"))
+	}
+
+	// Set the SDK's default identity explicitly, to find the process's
+	// pollers when checking readiness.
+	if clientOptions.Identity == "" {
+		hostname, _ := os.Hostname()
+		clientOptions.Identity = fmt.Sprintf("%d@%s@", os.Getpid(), hostname)
+	}
+
+	drain := newDrainTracker(clientOptions.MetricsHandler)
+	var health *healthServer
+	if healthAddr != "" {
+		health = newHealthServer(clientOptions.Identity, drain, newDebugInfo(clientOptions.Identity, workers, stickyCacheSize, tuner, shutdownDelay))
+		healthCtx, stopHealth := context.WithCancel(context.Background())
+		defer stopHealth()
+		go health.serve(healthCtx, healthAddr, metricsHTTP)
 	}
 
 	c, err := client.Dial(clientOptions)
@@ -132,37 +428,119 @@ func main() {
 	}
 	defer c.Close()
 
-	workerOptions := worker.Options{}
+	// The sticky cache is shared by every worker in the process.
+	if stickyCacheSize > 0 {
+		worker.SetStickyWorkflowCacheSize(stickyCacheSize)
+	}
 
-	if maxWorkflowPollers >= 0 {
-		workerOptions.WorkflowTaskPollerBehavior = worker.NewPollerBehaviorAutoscaling(worker.PollerBehaviorAutoscalingOptions{
-			MaximumNumberOfPollers: maxWorkflowPollers,
-		})
-	} else {
-		workerOptions.WorkflowTaskPollerBehavior = worker.NewPollerBehaviorSimpleMaximum(worker.PollerBehaviorSimpleMaximumOptions{})
+	// Like the sticky cache, the resource tuner's CPU and memory targets apply
+	// to the process, so its workers share one resource controller.
+	var resources *resourceController
+	if tuner.Kind == tunerResource {
+		resources = newResourceController(tuner.TargetCPU, tuner.TargetMemory)
 	}
 
-	if maxActivityPollers >= 0 {
-		workerOptions.ActivityTaskPollerBehavior = worker.NewPollerBehaviorAutoscaling(worker.PollerBehaviorAutoscalingOptions{
-			MaximumNumberOfPollers: maxActivityPollers,
-		})
-	} else {
-		workerOptions.ActivityTaskPollerBehavior = worker.NewPollerBehaviorSimpleMaximum(worker.PollerBehaviorSimpleMaximumOptions{})
+	// A worker that hits a fatal error, such as its namespace not existing,
+	// stops polling; the process shuts down and exits with an error rather
+	// than stay up doing nothing.
+	fatalErrs := make(chan error, len(workers))
+
+	var running []worker.Worker
+	for _, spec := range workers {
+		wc := c
+		if spec.Namespace != namespace {
+			wc, err = client.NewClientFromExisting(c, client.Options{
+				Namespace:      spec.Namespace,
+				Identity:       clientOptions.Identity,
+				MetricsHandler: clientOptions.MetricsHandler,
+				Interceptors:   clientOptions.Interceptors,
+			})
+			if err != nil {
+				log.Fatalf("Unable to create client for namespace %s: %v", spec.Namespace, err)
+			}
+			defer wc.Close()
+		}
+
+		log.Printf("Creating worker for %s", spec)
+
+		workerOptions := spec.Options
+		workerOptions.WorkflowTaskPollerBehavior = spec.WorkflowPollers.behavior()
+		workerOptions.ActivityTaskPollerBehavior = spec.ActivityPollers.behavior()
+		workerOptions.NexusTaskPollerBehavior = spec.NexusPollers.behavior()
+		spec.disablePolling(&workerOptions)
+		workerOptions.DeploymentOptions = spec.Versioning.deploymentOptions()
+		workerOptions.Interceptors = append(workerOptions.Interceptors, drain)
+		workerOptions.OnFatalError = func(err error) {
+			select {
+			case fatalErrs <- fmt.Errorf("%s: %w", spec, err):
+			default:
+			}
+		}
+		log.Printf("Workflow task pollers: %s", spec.WorkflowPollers)
+		log.Printf("Activity task pollers: %s", spec.ActivityPollers)
+		log.Printf("Nexus task pollers: %s", spec.NexusPollers)
+		log.Printf("Worker versioning: %s", spec.Versioning)
+		logWorkerOptions(workerOptions, stickyCacheSize)
+
+		if tuner.Kind != "" {
+			workerOptions.Tuner, err = newWorkerTuner(tuner, resources)
+			if err != nil {
+				log.Fatalf("Unable to create worker tuner: %v", err)
+			}
+			log.Printf("Worker tuner: %s", tuner)
+		}
+
+		w := worker.New(wc, spec.TaskQueue, workerOptions)
+		spec.register(w)
+
+		if err := w.Start(); err != nil {
+			log.Fatalf("Unable to start worker for %s: %v", spec, err)
+		}
+		running = append(running, w)
+		if health != nil {
+			health.addWorker(spec, wc)
+		}
+
+		log.Printf("Started worker for %s", spec)
 	}
+	drain.setReady(true)
 
-	// TODO: Support more worker options
+	var failure error
+	select {
+	case <-worker.InterruptCh():
+	case failure = <-fatalErrs:
+		log.Printf("Worker failed, shutting down: %v", failure)
+	}
 
-	w := worker.New(c, taskQueue, workerOptions)
+	// Report not ready first, so that rollouts see the worker going away
+	// before it stops taking work. A failed worker has already stopped taking
+	// work, so it stops straight away.
+	drain.setReady(false)
+	if failure == nil && shutdownDelay > 0 {
+		log.Printf("Marked not ready, stopping workers in %s", shutdownDelay)
+		time.Sleep(shutdownDelay)
+	}
 
-	w.RegisterWorkflowWithOptions(workflows.ExecuteActivityWorkflow, workflow.RegisterOptions{Name: "ExecuteActivity"})
-	w.RegisterWorkflowWithOptions(workflows.ReceiveSignalWorkflow, workflow.RegisterOptions{Name: "ReceiveSignal"})
-	w.RegisterWorkflowWithOptions(workflows.DSLWorkflow, workflow.RegisterOptions{Name: "DSL"})
-	w.RegisterActivityWithOptions(activities.SleepActivity, activity.RegisterOptions{Name: "Sleep"})
-	w.RegisterActivityWithOptions(activities.EchoActivity, activity.RegisterOptions{Name: "Echo"})
+	log.Printf("Stopping workers")
+	drain.startDrain()
+	var wg sync.WaitGroup
+	for _, w := range running {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w.Stop()
+		}()
+	}
+	wg.Wait()
+	logDrain(drain.report())
 
-	log.Printf("Starting worker for namespace: %s", namespace)
-	err = w.Run(worker.InterruptCh())
-	if err != nil {
-		log.Fatalf("Worker failed: %v", err)
+	// The drain metrics are set as the process exits, so report them to the
+	// registry now for the final push, and give Prometheus the final metrics
+	// delay to scrape them.
+	flushMetrics()
+	if scraped && finalMetricsDelay > 0 {
+		log.Printf("Serving final metrics for %s", finalMetricsDelay)
+		time.Sleep(finalMetricsDelay)
 	}
+	return failure
 }
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/temporalio/benchmark-workers/internal/devserver"
+	enumspb "go.temporal.io/api/enums/v1"
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/testsuite"
+)
+
+func buildWorker(t *testing.T) string {
+	bin := filepath.Join(t.TempDir(), "worker")
+	out, err := exec.Command("go", "build", "-o", bin, ".").CombinedOutput()
+	require.NoError(t, err, string(out))
+	return bin
+}
+
+func startWorker(t *testing.T, server *testsuite.DevServer, env ...string) {
+	cmd := exec.Command(buildWorker(t))
+	cmd.Env = append(os.Environ(), "TEMPORAL_GRPC_ENDPOINT="+server.FrontendHostPort())
+	cmd.Env = append(cmd.Env, env...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	require.NoError(t, cmd.Start())
+	t.Cleanup(func() {
+		cmd.Process.Signal(os.Interrupt)
+		cmd.Wait()
+	})
+}
+
+func TestWorkerRunsBenchmarkWorkflows(t *testing.T) {
+	server := devserver.Start(t)
+	startWorker(t, server)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	tests := []struct {
+		workflowType string
+		input        interface{}
+	}{
+		{"ExecuteActivity", map[string]interface{}{"Count": 2, "Activity": "Echo", "Input": map[string]interface{}{"Message": "test"}}},
+		{"DSL", []map[string]interface{}{{"a": "Echo", "i": map[string]interface{}{"Message": "test"}, "r": 2}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.workflowType, func(t *testing.T) {
+			run, err := server.Client().ExecuteWorkflow(ctx, client.StartWorkflowOptions{TaskQueue: "benchmark"}, tt.workflowType, tt.input)
+			require.NoError(t, err)
+			require.NoError(t, run.Get(ctx, nil))
+		})
+	}
+}
+
+func TestWorkerSplitRegistration(t *testing.T) {
+	server := devserver.Start(t)
+	startWorker(t, server, "TEMPORAL_WORKERS=role=workflows;role=activities,activity-pollers=autoscaling:min=1,max=4")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	run, err := server.Client().ExecuteWorkflow(ctx, client.StartWorkflowOptions{TaskQueue: "benchmark"}, "ExecuteActivity",
+		map[string]interface{}{"Count": 2, "Activity": "Echo", "Input": map[string]interface{}{"Message": "test"}})
+	require.NoError(t, err)
+	require.NoError(t, run.Get(ctx, nil))
+}
+
+func TestWorkerRoles(t *testing.T) {
+	server := devserver.Start(t)
+	startWorker(t, server, "TEMPORAL_WORKER_ROLE=workflows")
+	startWorker(t, server, "TEMPORAL_WORKER_ROLE=activities")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	run, err := server.Client().ExecuteWorkflow(ctx, client.StartWorkflowOptions{TaskQueue: "benchmark"}, "ExecuteActivity",
+		map[string]interface{}{"Count": 2, "Activity": "Sleep", "Input": map[string]interface{}{"SleepTimeInSeconds": 0}})
+	require.NoError(t, err)
+	require.NoError(t, run.Get(ctx, nil))
+}
+
+func TestWorkerVersionsSideBySide(t *testing.T) {
+	server := devserver.Start(t)
+	startWorker(t, server,
+		"TEMPORAL_DEPLOYMENT_NAME=benchmark",
+		"TEMPORAL_WORKFLOW_VERSIONING_BEHAVIOR=ExecuteActivity:pinned",
+		"TEMPORAL_WORKERS=build-id=v1;build-id=v2")
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	deployment := server.Client().WorkerDeploymentClient().GetHandle("benchmark")
+	for _, buildID := range []string{"v1", "v2"} {
+		// The version can't be made current until its worker has polled.
+		require.EventuallyWithT(t, func(c *assert.CollectT) {
+			_, err := deployment.SetCurrentVersion(ctx, client.WorkerDeploymentSetCurrentVersionOptions{BuildID: buildID})
+			assert.NoError(c, err)
+		}, 30*time.Second, 500*time.Millisecond)
+
+		run, err := server.Client().ExecuteWorkflow(ctx, client.StartWorkflowOptions{TaskQueue: "benchmark"}, "ExecuteActivity",
+			map[string]interface{}{"Count": 1, "Activity": "Echo", "Input": map[string]interface{}{"Message": "test"}})
+		require.NoError(t, err)
+		require.NoError(t, run.Get(ctx, nil))
+
+		desc, err := server.Client().DescribeWorkflowExecution(ctx, run.GetID(), run.GetRunID())
+		require.NoError(t, err)
+		info := desc.GetWorkflowExecutionInfo().GetVersioningInfo()
+		require.Equal(t, enumspb.VERSIONING_BEHAVIOR_PINNED, info.GetBehavior())
+		require.Equal(t, buildID, info.GetDeploymentVersion().GetBuildId())
+	}
+}
+
+func TestWorkerDrainsOnShutdown(t *testing.T) {
+	server := devserver.Start(t)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	metricsAddr := l.Addr().String()
+	l.Close()
+
+	var out bytes.Buffer
+	cmd := exec.Command(buildWorker(t))
+	cmd.Env = append(os.Environ(),
+		"TEMPORAL_GRPC_ENDPOINT="+server.FrontendHostPort(),
+		"TEMPORAL_WORKER_STOP_TIMEOUT=4s",
+		"TEMPORAL_SHUTDOWN_DELAY=1s",
+		"TEMPORAL_FINAL_METRICS_DELAY=1s",
+		"PROMETHEUS_ENDPOINT="+metricsAddr)
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	require.NoError(t, cmd.Start())
+	t.Cleanup(func() {
+		cmd.Process.Kill()
+		cmd.Wait()
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	// One activity finishes during the drain, the other outlasts the stop
+	// timeout and is abandoned.
+	var runs []client.WorkflowRun
+	for _, seconds := range []int{3, 60} {
+		run, err := server.Client().ExecuteWorkflow(ctx, client.StartWorkflowOptions{TaskQueue: "benchmark"}, "ExecuteActivity",
+			map[string]interface{}{"Count": 1, "Activity": "Sleep", "Input": map[string]interface{}{"SleepTimeInSeconds": seconds}})
+		require.NoError(t, err)
+		runs = append(runs, run)
+	}
+	require.EventuallyWithT(t, func(c *assert.CollectT) {
+		for _, run := range runs {
+			desc, err := server.Client().DescribeWorkflowExecution(ctx, run.GetID(), run.GetRunID())
+			if assert.NoError(c, err) && assert.Len(c, desc.GetPendingActivities(), 1) {
+				assert.Equal(c, enumspb.PENDING_ACTIVITY_STATE_STARTED, desc.GetPendingActivities()[0].GetState())
+			}
+		}
+	}, 20*time.Second, 100*time.Millisecond)
+
+	require.NoError(t, cmd.Process.Signal(os.Interrupt))
+
+	// The drain metrics can be scraped before the worker exits.
+	require.EventuallyWithT(t, func(c *assert.CollectT) {
+		resp, err := http.Get("http://" + metricsAddr + "/metrics")
+		if !assert.NoError(c, err) {
+			return
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		assert.Regexp(c, `(?m)^benchmark_worker_drain_abandoned(\{.*\})? 1$`, string(body))
+	}, 20*time.Second, 100*time.Millisecond)
+
+	require.NoError(t, cmd.Wait())
+	require.Contains(t, out.String(), "2 activities in flight, 1 completed, 1 abandoned")
+}
+
+func TestWorkerHealthEndpoints(t *testing.T) {
+	server := devserver.Start(t)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := l.Addr().String()
+	l.Close()
+
+	// Metrics share the health listener.
+	startWorker(t, server, "HEALTH_ENDPOINT="+addr, "PROMETHEUS_ENDPOINT="+addr)
+
+	get := func(path string) (int, string) {
+		resp, err := http.Get("http://" + addr + path)
+		if err != nil {
+			return 0, err.Error()
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(body)
+	}
+
+	require.EventuallyWithT(t, func(c *assert.CollectT) {
+		code, body := get("/healthz")
+		assert.Equal(c, http.StatusOK, code, body)
+	}, 20*time.Second, 100*time.Millisecond)
+
+	require.EventuallyWithT(t, func(c *assert.CollectT) {
+		code, body := get("/readyz")
+		assert.Equal(c, http.StatusOK, code, body)
+	}, 20*time.Second, 250*time.Millisecond)
+
+	code, body := get("/debug/info")
+	require.Equal(t, http.StatusOK, code)
+	var info debugInfo
+	require.NoError(t, json.Unmarshal([]byte(body), &info))
+	require.Len(t, info.Workers, 1)
+	require.Equal(t, "benchmark", info.Workers[0].TaskQueue)
+	require.Contains(t, info.Workers[0].Workflows, "ExecuteActivity")
+	require.Contains(t, info.Workers[0].Activities, "Sleep")
+
+	// Metrics are flushed to the registry on the reporting interval.
+	require.EventuallyWithT(t, func(c *assert.CollectT) {
+		code, body := get("/metrics")
+		assert.Equal(c, http.StatusOK, code)
+		assert.Contains(c, body, "benchmark_worker_ready 1")
+	}, 10*time.Second, 250*time.Millisecond)
+}
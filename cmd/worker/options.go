@@ -0,0 +1,115 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"go.temporal.io/sdk/worker"
+)
+
+// workerOption is a tunable worker setting, for logging at startup.
+type workerOption struct {
+	name  string
+	value interface{}
+	// set is false if the SDK default is used.
+	set bool
+}
+
+// tunedOptions lists the worker settings that can be configured, with the
+// process wide sticky workflow cache size alongside the worker.Options.
+func tunedOptions(o worker.Options, stickyCacheSize int) []workerOption {
+	return []workerOption{
+		{"MaxConcurrentActivityExecutionSize", o.MaxConcurrentActivityExecutionSize, o.MaxConcurrentActivityExecutionSize > 0},
+		{"MaxConcurrentWorkflowTaskExecutionSize", o.MaxConcurrentWorkflowTaskExecutionSize, o.MaxConcurrentWorkflowTaskExecutionSize > 0},
+		{"MaxConcurrentLocalActivityExecutionSize", o.MaxConcurrentLocalActivityExecutionSize, o.MaxConcurrentLocalActivityExecutionSize > 0},
+		{"TaskQueueActivitiesPerSecond", o.TaskQueueActivitiesPerSecond, o.TaskQueueActivitiesPerSecond > 0},
+		{"WorkerActivitiesPerSecond", o.WorkerActivitiesPerSecond, o.WorkerActivitiesPerSecond > 0},
+		{"StickyScheduleToStartTimeout", o.StickyScheduleToStartTimeout, o.StickyScheduleToStartTimeout > 0},
+		{"WorkerStopTimeout", o.WorkerStopTimeout, o.WorkerStopTimeout > 0},
+		{"DeadlockDetectionTimeout", o.DeadlockDetectionTimeout, o.DeadlockDetectionTimeout > 0},
+		{"DisableEagerActivities", o.DisableEagerActivities, true},
+		{"StickyWorkflowCacheSize", stickyCacheSize, stickyCacheSize > 0},
+	}
+}
+
+// optionValues reads worker settings from their flag or environment variable,
+// like the get*Value functions, except that an environment variable that fails
+// to parse is an error rather than silently ignored.
+type optionValues struct {
+	errs []error
+}
+
+func (v *optionValues) intValue(flagName, envName string, flagValue, defaultValue int) int {
+	return optionValue(v, flagName, envName, flagValue, defaultValue, strconv.Atoi)
+}
+
+func (v *optionValues) floatValue(flagName, envName string, flagValue, defaultValue float64) float64 {
+	return optionValue(v, flagName, envName, flagValue, defaultValue, func(s string) (float64, error) {
+		return strconv.ParseFloat(s, 64)
+	})
+}
+
+func (v *optionValues) durationValue(flagName, envName string, flagValue, defaultValue time.Duration) time.Duration {
+	return optionValue(v, flagName, envName, flagValue, defaultValue, time.ParseDuration)
+}
+
+func (v *optionValues) boolValue(flagName, envName string, flagValue, defaultValue bool) bool {
+	return optionValue(v, flagName, envName, flagValue, defaultValue, strconv.ParseBool)
+}
+
+// err returns the errors for every value that failed to parse.
+func (v *optionValues) err() error {
+	return errors.Join(v.errs...)
+}
+
+func optionValue[T any](v *optionValues, flagName, envName string, flagValue, defaultValue T, parse func(string) (T, error)) T {
+	if flagsSet[flagName] {
+		return flagValue
+	}
+	envValue := os.Getenv(envName)
+	if envValue == "" {
+		return defaultValue
+	}
+	parsed, err := parse(envValue)
+	if err != nil {
+		v.errs = append(v.errs, fmt.Errorf("invalid %s %q", envName, envValue))
+		return defaultValue
+	}
+	return parsed
+}
+
+// validateWorkerOptions checks that none of the configured worker settings are
+// negative.
+func validateWorkerOptions(o worker.Options, stickyCacheSize int) error {
+	var errs []error
+	for _, opt := range tunedOptions(o, stickyCacheSize) {
+		negative := false
+		switch v := opt.value.(type) {
+		case int:
+			negative = v < 0
+		case float64:
+			negative = v < 0
+		case time.Duration:
+			negative = v < 0
+		}
+		if negative {
+			errs = append(errs, fmt.Errorf("%s must not be negative", opt.name))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// logWorkerOptions logs each configurable worker setting.
+func logWorkerOptions(o worker.Options, stickyCacheSize int) {
+	for _, opt := range tunedOptions(o, stickyCacheSize) {
+		if opt.set {
+			log.Printf("Worker option %s: %v", opt.name, opt.value)
+		} else {
+			log.Printf("Worker option %s: SDK default", opt.name)
+		}
+	}
+}
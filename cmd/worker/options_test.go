@@ -0,0 +1,48 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/worker"
+)
+
+func TestValidateWorkerOptions(t *testing.T) {
+	require.NoError(t, validateWorkerOptions(worker.Options{}, 0))
+	require.NoError(t, validateWorkerOptions(worker.Options{
+		MaxConcurrentActivityExecutionSize: 200,
+		WorkerActivitiesPerSecond:          50,
+		WorkerStopTimeout:                  30 * time.Second,
+		DisableEagerActivities:             true,
+	}, 5000))
+
+	err := validateWorkerOptions(worker.Options{
+		MaxConcurrentWorkflowTaskExecutionSize: -1,
+		TaskQueueActivitiesPerSecond:           -5,
+		DeadlockDetectionTimeout:               -time.Second,
+	}, -1)
+	require.ErrorContains(t, err, "MaxConcurrentWorkflowTaskExecutionSize must not be negative")
+	require.ErrorContains(t, err, "TaskQueueActivitiesPerSecond must not be negative")
+	require.ErrorContains(t, err, "DeadlockDetectionTimeout must not be negative")
+	require.ErrorContains(t, err, "StickyWorkflowCacheSize must not be negative")
+}
+
+func TestOptionValues(t *testing.T) {
+	t.Setenv("TEMPORAL_MAX_CONCURRENT_ACTIVITY_EXECUTION_SIZE", "200")
+	t.Setenv("TEMPORAL_WORKER_STOP_TIMEOUT", "30s")
+	var options optionValues
+	require.Equal(t, 200, options.intValue("max-concurrent-activities", "TEMPORAL_MAX_CONCURRENT_ACTIVITY_EXECUTION_SIZE", 0, 0))
+	require.Equal(t, 30*time.Second, options.durationValue("worker-stop-timeout", "TEMPORAL_WORKER_STOP_TIMEOUT", 0, 0))
+	require.Equal(t, 0.0, options.floatValue("worker-activities-per-second", "TEMPORAL_WORKER_ACTIVITIES_PER_SECOND", 0, 0))
+	require.NoError(t, options.err())
+
+	// A typo is reported rather than replaced by the default.
+	t.Setenv("TEMPORAL_MAX_CONCURRENT_ACTIVITY_EXECUTION_SIZE", "1O")
+	t.Setenv("TEMPORAL_DISABLE_EAGER_ACTIVITIES", "yes please")
+	options.intValue("max-concurrent-activities", "TEMPORAL_MAX_CONCURRENT_ACTIVITY_EXECUTION_SIZE", 0, 0)
+	options.boolValue("disable-eager-activities", "TEMPORAL_DISABLE_EAGER_ACTIVITIES", false, false)
+	err := options.err()
+	require.ErrorContains(t, err, `invalid TEMPORAL_MAX_CONCURRENT_ACTIVITY_EXECUTION_SIZE "1O"`)
+	require.ErrorContains(t, err, `invalid TEMPORAL_DISABLE_EAGER_ACTIVITIES "yes please"`)
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"go.temporal.io/sdk/worker"
+)
+
+// Poller modes that can be selected for each type of task.
+const (
+	pollerSimple      = "simple"
+	pollerAutoscaling = "autoscaling"
+)
+
+// pollerSpec describes how the worker polls for one type of task. Zero values
+// leave the SDK default.
+type pollerSpec struct {
+	Mode    string
+	Min     int
+	Initial int
+	Max     int
+}
+
+// parsePollerSpec parses "simple:N" (or just "N") for a fixed maximum of N
+// pollers, or "autoscaling:min=M,initial=I,max=X" for pollers scaled by the
+// SDK between M and X, starting at I. Any autoscaling value, or all of them
+// along with the colon, may be left out.
+func parsePollerSpec(s string) (pollerSpec, error) {
+	mode, params, _ := strings.Cut(strings.TrimSpace(s), ":")
+
+	if n, err := strconv.Atoi(mode); err == nil && params == "" {
+		mode, params = pollerSimple, strconv.Itoa(n)
+	}
+
+	spec := pollerSpec{Mode: mode}
+	switch mode {
+	case pollerSimple:
+		if params == "" {
+			return spec, nil
+		}
+		n, err := strconv.Atoi(params)
+		if err != nil || n < 1 {
+			return pollerSpec{}, fmt.Errorf("simple pollers need a maximum of at least 1, got %q", params)
+		}
+		spec.Max = n
+		return spec, nil
+	case pollerAutoscaling:
+	default:
+		return pollerSpec{}, fmt.Errorf("unknown poller mode %q", mode)
+	}
+
+	if params != "" {
+		for _, param := range strings.Split(params, ",") {
+			key, value, ok := strings.Cut(param, "=")
+			if !ok {
+				return pollerSpec{}, fmt.Errorf("invalid autoscaling option %q", param)
+			}
+			n, err := strconv.Atoi(strings.TrimSpace(value))
+			if err != nil || n < 1 {
+				return pollerSpec{}, fmt.Errorf("autoscaling %s must be at least 1, got %q", key, value)
+			}
+			switch strings.TrimSpace(key) {
+			case "min":
+				spec.Min = n
+			case "initial":
+				spec.Initial = n
+			case "max":
+				spec.Max = n
+			default:
+				return pollerSpec{}, fmt.Errorf("unknown autoscaling option %q", key)
+			}
+		}
+	}
+
+	var errs []error
+	if spec.Min > 0 && spec.Initial > 0 && spec.Min > spec.Initial {
+		errs = append(errs, errors.New("autoscaling min must not be more than initial"))
+	}
+	if spec.Initial > 0 && spec.Max > 0 && spec.Initial > spec.Max {
+		errs = append(errs, errors.New("autoscaling initial must not be more than max"))
+	}
+	if spec.Min > 0 && spec.Max > 0 && spec.Min > spec.Max {
+		errs = append(errs, errors.New("autoscaling min must not be more than max"))
+	}
+	if err := errors.Join(errs...); err != nil {
+		return pollerSpec{}, err
+	}
+	return spec, nil
+}
+
+func (p pollerSpec) behavior() worker.PollerBehavior {
+	if p.Mode == pollerAutoscaling {
+		return worker.NewPollerBehaviorAutoscaling(worker.PollerBehaviorAutoscalingOptions{
+			MinimumNumberOfPollers: p.Min,
+			InitialNumberOfPollers: p.Initial,
+			MaximumNumberOfPollers: p.Max,
+		})
+	}
+	return worker.NewPollerBehaviorSimpleMaximum(worker.PollerBehaviorSimpleMaximumOptions{
+		MaximumNumberOfPollers: p.Max,
+	})
+}
+
+// String describes the spec for logging.
+func (p pollerSpec) String() string {
+	value := func(n int) string {
+		if n > 0 {
+			return strconv.Itoa(n)
+		}
+		return "default"
+	}
+	if p.Mode == pollerAutoscaling {
+		return fmt.Sprintf("autoscaling, min %s, initial %s, max %s", value(p.Min), value(p.Initial), value(p.Max))
+	}
+	return fmt.Sprintf("simple, max %s", value(p.Max))
+}
+
+// resolvePollerSpec parses spec if given, and otherwise falls back to the
+// older maximum poller setting: autoscaling up to legacyMax if it is zero or
+// more, or the SDK's simple default.
+func resolvePollerSpec(spec string, legacyMax int) (pollerSpec, error) {
+	if spec == "" {
+		if legacyMax >= 0 {
+			return pollerSpec{Mode: pollerAutoscaling, Max: legacyMax}, nil
+		}
+		return pollerSpec{Mode: pollerSimple}, nil
+	}
+	if legacyMax >= 0 {
+		return pollerSpec{}, errors.New("a poller mode can't be combined with a maximum number of pollers")
+	}
+	return parsePollerSpec(spec)
+}
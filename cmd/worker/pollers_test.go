@@ -0,0 +1,44 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePollerSpec(t *testing.T) {
+	tests := []struct {
+		spec string
+		want pollerSpec
+	}{
+		{"16", pollerSpec{Mode: pollerSimple, Max: 16}},
+		{"simple", pollerSpec{Mode: pollerSimple}},
+		{"simple:4", pollerSpec{Mode: pollerSimple, Max: 4}},
+		{"autoscaling", pollerSpec{Mode: pollerAutoscaling}},
+		{"autoscaling:max=50", pollerSpec{Mode: pollerAutoscaling, Max: 50}},
+		{"autoscaling:min=2,initial=10,max=50", pollerSpec{Mode: pollerAutoscaling, Min: 2, Initial: 10, Max: 50}},
+	}
+	for _, tt := range tests {
+		got, err := parsePollerSpec(tt.spec)
+		require.NoError(t, err, tt.spec)
+		require.Equal(t, tt.want, got, tt.spec)
+	}
+
+	for _, spec := range []string{"0", "simple:x", "fixed:2", "autoscaling:max", "autoscaling:step=2", "autoscaling:min=10,max=5", "autoscaling:initial=20,max=10"} {
+		_, err := parsePollerSpec(spec)
+		require.Error(t, err, spec)
+	}
+}
+
+func TestResolvePollerSpec(t *testing.T) {
+	spec, err := resolvePollerSpec("", -1)
+	require.NoError(t, err)
+	require.Equal(t, pollerSpec{Mode: pollerSimple}, spec)
+
+	spec, err = resolvePollerSpec("", 8)
+	require.NoError(t, err)
+	require.Equal(t, pollerSpec{Mode: pollerAutoscaling, Max: 8}, spec)
+
+	_, err = resolvePollerSpec("simple:4", 8)
+	require.Error(t, err)
+}
@@ -0,0 +1,319 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shirou/gopsutil/v4/cpu"
+	"github.com/shirou/gopsutil/v4/mem"
+	"go.temporal.io/sdk/worker"
+)
+
+// Worker tuners that can be selected. Without a tuner the worker uses the
+// classic MaxConcurrent*ExecutionSize options.
+const (
+	tunerFixed    = "fixed"
+	tunerResource = "resource"
+)
+
+// tunerConfig configures the worker's WorkerTuner.
+type tunerConfig struct {
+	Kind string
+	// WorkflowSlots, ActivitySlots and LocalActivitySlots fix the number of
+	// slots of each type. With the resource tuner, a type given a fixed
+	// number of slots doesn't scale with resource use.
+	WorkflowSlots      int
+	ActivitySlots      int
+	LocalActivitySlots int
+	// TargetCPU and TargetMemory are the utilization, from 0 to 1, the
+	// resource tuner stops issuing slots at.
+	TargetCPU    float64
+	TargetMemory float64
+}
+
+// validate checks c, and that it doesn't conflict with the classic
+// concurrency options in o.
+func (c tunerConfig) validate(o worker.Options) error {
+	var errs []error
+	switch c.Kind {
+	case "", tunerFixed, tunerResource:
+	default:
+		errs = append(errs, fmt.Errorf("unknown tuner %q", c.Kind))
+	}
+	if c.WorkflowSlots < 0 || c.ActivitySlots < 0 || c.LocalActivitySlots < 0 {
+		errs = append(errs, errors.New("slot counts must not be negative"))
+	}
+	if c.Kind == "" && (c.WorkflowSlots > 0 || c.ActivitySlots > 0 || c.LocalActivitySlots > 0) {
+		errs = append(errs, errors.New("slot counts need a tuner"))
+	}
+	if c.Kind != "" && (o.MaxConcurrentActivityExecutionSize > 0 ||
+		o.MaxConcurrentWorkflowTaskExecutionSize > 0 ||
+		o.MaxConcurrentLocalActivityExecutionSize > 0) {
+		errs = append(errs, errors.New("a tuner can't be combined with max concurrent execution sizes"))
+	}
+	if c.Kind == tunerResource {
+		if c.TargetCPU <= 0 || c.TargetCPU > 1 {
+			errs = append(errs, errors.New("target CPU utilization must be greater than 0 and at most 1"))
+		}
+		if c.TargetMemory <= 0 || c.TargetMemory > 1 {
+			errs = append(errs, errors.New("target memory utilization must be greater than 0 and at most 1"))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// String describes the tuner for logging.
+func (c tunerConfig) String() string {
+	slots := func(n int) string {
+		switch {
+		case n > 0:
+			return strconv.Itoa(n)
+		case c.Kind == tunerResource:
+			return "resource-based"
+		}
+		return "SDK default"
+	}
+	s := fmt.Sprintf("%s, workflow slots %s, activity slots %s, local activity slots %s",
+		c.Kind, slots(c.WorkflowSlots), slots(c.ActivitySlots), slots(c.LocalActivitySlots))
+	if c.Kind == tunerResource {
+		s += fmt.Sprintf(", target CPU %.2f, target memory %.2f", c.TargetCPU, c.TargetMemory)
+	}
+	return s
+}
+
+// resourceSlotOptions bound the slots a resource-based slot supplier issues.
+type resourceSlotOptions struct {
+	MinSlots int
+	MaxSlots int
+	// RampThrottle is the minimum time between issuing slots above MinSlots,
+	// so that the resource use of new tasks shows up before more are started.
+	RampThrottle time.Duration
+}
+
+// Defaults for each slot type, as used by the SDK's resource-based tuner.
+var (
+	workflowResourceSlots = resourceSlotOptions{MinSlots: 5, MaxSlots: 1000}
+	activityResourceSlots = resourceSlotOptions{MinSlots: 1, MaxSlots: 1000, RampThrottle: 50 * time.Millisecond}
+)
+
+// newWorkerTuner creates the tuner described by c for one worker, or returns
+// nil if none is configured. The resource tuner issues slots based on
+// controller, which is shared by all the process's workers so that the
+// process as a whole is held to the CPU and memory targets.
+func newWorkerTuner(c tunerConfig, controller *resourceController) (worker.WorkerTuner, error) {
+	switch c.Kind {
+	case tunerFixed:
+		return worker.NewFixedSizeTuner(worker.FixedSizeTunerOptions{
+			NumWorkflowSlots:      c.WorkflowSlots,
+			NumActivitySlots:      c.ActivitySlots,
+			NumLocalActivitySlots: c.LocalActivitySlots,
+		})
+	case tunerResource:
+		supplier := func(fixed int, o resourceSlotOptions) (worker.SlotSupplier, error) {
+			if fixed > 0 {
+				return worker.NewFixedSizeSlotSupplier(fixed)
+			}
+			return &resourceSlotSupplier{controller: controller, options: o}, nil
+		}
+
+		workflowSupplier, err := supplier(c.WorkflowSlots, workflowResourceSlots)
+		if err != nil {
+			return nil, err
+		}
+		activitySupplier, err := supplier(c.ActivitySlots, activityResourceSlots)
+		if err != nil {
+			return nil, err
+		}
+		localActivitySupplier, err := supplier(c.LocalActivitySlots, activityResourceSlots)
+		if err != nil {
+			return nil, err
+		}
+		nexusSupplier, err := worker.NewFixedSizeSlotSupplier(1000)
+		if err != nil {
+			return nil, err
+		}
+		return worker.NewCompositeTuner(worker.CompositeTunerOptions{
+			WorkflowSlotSupplier:        workflowSupplier,
+			ActivitySlotSupplier:        activitySupplier,
+			LocalActivitySlotSupplier:   localActivitySupplier,
+			NexusSlotSupplier:           nexusSupplier,
+			SessionActivitySlotSupplier: activitySupplier,
+		})
+	}
+	return nil, nil
+}
+
+// resourceSlotSupplier issues slots while CPU and memory use are below their
+// targets, between a minimum and maximum number of slots.
+type resourceSlotSupplier struct {
+	controller *resourceController
+	options    resourceSlotOptions
+
+	mu         sync.Mutex
+	lastIssued time.Time
+}
+
+func (s *resourceSlotSupplier) ReserveSlot(ctx context.Context, info worker.SlotReservationInfo) (*worker.SlotPermit, error) {
+	for {
+		if permit := s.TryReserveSlot(info); permit != nil {
+			return permit, nil
+		}
+		select {
+		case <-time.After(10 * time.Millisecond):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+func (s *resourceSlotSupplier) TryReserveSlot(info worker.SlotReservationInfo) *worker.SlotPermit {
+	issued := info.NumIssuedSlots()
+	if issued < s.options.MinSlots {
+		return &worker.SlotPermit{}
+	}
+	if issued >= s.options.MaxSlots {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if time.Since(s.lastIssued) < s.options.RampThrottle || !s.controller.allow() {
+		return nil
+	}
+	s.lastIssued = time.Now()
+	return &worker.SlotPermit{}
+}
+
+func (s *resourceSlotSupplier) MarkSlotUsed(worker.SlotMarkUsedInfo) {}
+
+func (s *resourceSlotSupplier) ReleaseSlot(worker.SlotReleaseInfo) {}
+
+func (s *resourceSlotSupplier) MaxSlots() int {
+	return s.options.MaxSlots
+}
+
+// resourceSampleInterval is how long a CPU and memory reading is reused for.
+const resourceSampleInterval = 100 * time.Millisecond
+
+// resourceController compares the process's CPU and memory utilization to
+// their targets. Inside a cgroup v2 container with limits, utilization is of
+// the container's limits; otherwise it is of the host.
+type resourceController struct {
+	targetCPU    float64
+	targetMemory float64
+
+	mu           sync.Mutex
+	sampledAt    time.Time
+	cpuUsage     float64
+	memoryUsage  float64
+	lastCgroupAt time.Time
+	lastCgroupUS uint64
+}
+
+func newResourceController(targetCPU, targetMemory float64) *resourceController {
+	return &resourceController{targetCPU: targetCPU, targetMemory: targetMemory}
+}
+
+// allow reports whether both CPU and memory utilization are below target.
+func (r *resourceController) allow() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if time.Since(r.sampledAt) >= resourceSampleInterval {
+		r.sample()
+	}
+	return r.cpuUsage < r.targetCPU && r.memoryUsage < r.targetMemory
+}
+
+func (r *resourceController) sample() {
+	now := time.Now()
+	r.sampledAt = now
+
+	if usage, ok := cgroupMemoryUsage(); ok {
+		r.memoryUsage = usage
+	} else if vm, err := mem.VirtualMemory(); err == nil {
+		r.memoryUsage = vm.UsedPercent / 100
+	}
+
+	if usec, cpus, ok := cgroupCPU(); ok {
+		if !r.lastCgroupAt.IsZero() && usec >= r.lastCgroupUS {
+			elapsed := now.Sub(r.lastCgroupAt).Microseconds()
+			r.cpuUsage = float64(usec-r.lastCgroupUS) / float64(elapsed) / cpus
+		}
+		r.lastCgroupAt, r.lastCgroupUS = now, usec
+	} else if percent, err := cpu.Percent(0, false); err == nil && len(percent) > 0 {
+		// Measured since the previous call.
+		r.cpuUsage = percent[0] / 100
+	}
+}
+
+// cgroupDir is where the process's cgroup v2 controllers are mounted in a
+// container.
+const cgroupDir = "/sys/fs/cgroup"
+
+// cgroupMemoryUsage returns memory use as a fraction of the cgroup's limit, if
+// it has one.
+func cgroupMemoryUsage() (float64, bool) {
+	limit, err := readCgroupFile("memory.max")
+	if err != nil || limit == "max" {
+		return 0, false
+	}
+	current, err := readCgroupFile("memory.current")
+	if err != nil {
+		return 0, false
+	}
+	l, err1 := strconv.ParseFloat(limit, 64)
+	c, err2 := strconv.ParseFloat(current, 64)
+	if err1 != nil || err2 != nil || l <= 0 {
+		return 0, false
+	}
+	return c / l, true
+}
+
+// cgroupCPU returns the CPU time used by the cgroup in microseconds and its
+// limit in CPUs, if it has one.
+func cgroupCPU() (uint64, float64, bool) {
+	limit, err := readCgroupFile("cpu.max")
+	if err != nil {
+		return 0, 0, false
+	}
+	fields := strings.Fields(limit)
+	if len(fields) != 2 || fields[0] == "max" {
+		return 0, 0, false
+	}
+	quota, err1 := strconv.ParseFloat(fields[0], 64)
+	period, err2 := strconv.ParseFloat(fields[1], 64)
+	if err1 != nil || err2 != nil || quota <= 0 || period <= 0 {
+		return 0, 0, false
+	}
+
+	stat, err := readCgroupFile("cpu.stat")
+	if err != nil {
+		return 0, 0, false
+	}
+	for _, line := range strings.Split(stat, "\n") {
+		if v, ok := strings.CutPrefix(line, "usage_usec "); ok {
+			usec, err := strconv.ParseUint(v, 10, 64)
+			if err != nil {
+				return 0, 0, false
+			}
+			return usec, quota / period, true
+		}
+	}
+	return 0, 0, false
+}
+
+func readCgroupFile(name string) (string, error) {
+	b, err := os.ReadFile(cgroupDir + "/" + name)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(b)), nil
+}
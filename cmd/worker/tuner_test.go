@@ -0,0 +1,71 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/worker"
+)
+
+func TestTunerConfigValidate(t *testing.T) {
+	require.NoError(t, tunerConfig{}.validate(worker.Options{MaxConcurrentActivityExecutionSize: 10}))
+	require.NoError(t, tunerConfig{Kind: tunerFixed, ActivitySlots: 10}.validate(worker.Options{}))
+	require.NoError(t, tunerConfig{Kind: tunerResource, WorkflowSlots: 10, TargetCPU: 0.8, TargetMemory: 0.8}.validate(worker.Options{}))
+
+	require.ErrorContains(t, tunerConfig{Kind: "auto"}.validate(worker.Options{}), `unknown tuner "auto"`)
+	require.ErrorContains(t, tunerConfig{ActivitySlots: 10}.validate(worker.Options{}), "slot counts need a tuner")
+	require.ErrorContains(t, tunerConfig{Kind: tunerFixed}.validate(worker.Options{MaxConcurrentActivityExecutionSize: 10}), "can't be combined")
+	require.ErrorContains(t, tunerConfig{Kind: tunerResource, TargetCPU: 1.5, TargetMemory: 0.8}.validate(worker.Options{}), "target CPU")
+}
+
+// reservationInfo reports a fixed number of issued slots.
+type reservationInfo struct {
+	worker.SlotReservationInfo
+	issued int
+}
+
+func (i reservationInfo) NumIssuedSlots() int { return i.issued }
+
+func TestResourceSlotSupplier(t *testing.T) {
+	controller := newResourceController(0.8, 0.8)
+	controller.sampledAt = time.Now().Add(time.Hour)
+	s := &resourceSlotSupplier{controller: controller, options: resourceSlotOptions{MinSlots: 2, MaxSlots: 4, RampThrottle: time.Hour}}
+
+	// Slots are issued up to the maximum while resources are below target,
+	// throttled above the minimum.
+	require.NotNil(t, s.TryReserveSlot(reservationInfo{issued: 0}))
+	require.NotNil(t, s.TryReserveSlot(reservationInfo{issued: 2}))
+	require.Nil(t, s.TryReserveSlot(reservationInfo{issued: 3}))
+	require.Nil(t, s.TryReserveSlot(reservationInfo{issued: 4}))
+
+	// Over target, only the minimum is issued.
+	s.options.RampThrottle = 0
+	controller.cpuUsage = 0.9
+	require.NotNil(t, s.TryReserveSlot(reservationInfo{issued: 1}))
+	require.Nil(t, s.TryReserveSlot(reservationInfo{issued: 2}))
+}
+
+func TestWorkerTunersShareResourceController(t *testing.T) {
+	controller := newResourceController(0.8, 0.8)
+	controller.sampledAt = time.Now().Add(time.Hour)
+	config := tunerConfig{Kind: tunerResource, TargetCPU: 0.8, TargetMemory: 0.8}
+
+	var suppliers []worker.SlotSupplier
+	for range 2 {
+		tuner, err := newWorkerTuner(config, controller)
+		require.NoError(t, err)
+		suppliers = append(suppliers, tuner.GetActivityTaskSlotSupplier())
+	}
+
+	// Over target on the shared controller, every worker is held back.
+	controller.cpuUsage = 0.9
+	for _, s := range suppliers {
+		require.Nil(t, s.TryReserveSlot(reservationInfo{issued: 1}))
+	}
+
+	controller.cpuUsage = 0.5
+	for _, s := range suppliers {
+		require.NotNil(t, s.TryReserveSlot(reservationInfo{issued: 1}))
+	}
+}
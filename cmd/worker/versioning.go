@@ -0,0 +1,133 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/temporalio/benchmark-workers/workflows"
+	"go.temporal.io/sdk/worker"
+	"go.temporal.io/sdk/workflow"
+)
+
+// Versioning behaviors that can be given to workflows.
+const (
+	versioningPinned      = "pinned"
+	versioningAutoUpgrade = "auto-upgrade"
+)
+
+// versioningConfig opts a worker into Worker Deployment Versioning.
+type versioningConfig struct {
+	DeploymentName string
+	BuildID        string
+	// DefaultBehavior applies to workflows without a behavior of their own,
+	// and is auto-upgrade if empty.
+	DefaultBehavior string
+	// WorkflowBehaviors are the behaviors of individual workflows, by name.
+	WorkflowBehaviors map[string]string
+}
+
+func (c versioningConfig) enabled() bool {
+	return c.DeploymentName != "" || c.BuildID != ""
+}
+
+// parseWorkflowBehaviors parses comma separated workflow:behavior pairs, e.g.
+// "DSL:pinned,ExecuteActivity:auto-upgrade".
+func parseWorkflowBehaviors(s string) (map[string]string, error) {
+	if s == "" {
+		return nil, nil
+	}
+	behaviors := make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		name, behavior, ok := strings.Cut(strings.TrimSpace(pair), ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid workflow versioning behavior %q, expected workflow:behavior", pair)
+		}
+		behaviors[name] = behavior
+	}
+	return behaviors, nil
+}
+
+func parseVersioningBehavior(s string) (workflow.VersioningBehavior, error) {
+	switch s {
+	case "", versioningAutoUpgrade:
+		return workflow.VersioningBehaviorAutoUpgrade, nil
+	case versioningPinned:
+		return workflow.VersioningBehaviorPinned, nil
+	}
+	return workflow.VersioningBehaviorUnspecified, fmt.Errorf("unknown versioning behavior %q, expected %s or %s", s, versioningPinned, versioningAutoUpgrade)
+}
+
+// validate checks that the deployment is fully identified and that each
+// behavior is known.
+func (c versioningConfig) validate() error {
+	var errs []error
+	if c.enabled() && (c.DeploymentName == "" || c.BuildID == "") {
+		errs = append(errs, errors.New("a deployment name and build ID must be given together"))
+	}
+	if !c.enabled() && (c.DefaultBehavior != "" || len(c.WorkflowBehaviors) > 0) {
+		errs = append(errs, errors.New("versioning behaviors need a deployment name and build ID"))
+	}
+	if _, err := parseVersioningBehavior(c.DefaultBehavior); err != nil {
+		errs = append(errs, err)
+	}
+	for name, behavior := range c.WorkflowBehaviors {
+		if !slices.Contains(workflows.Names, name) {
+			errs = append(errs, fmt.Errorf("unknown workflow %q, expected one of %s", name, strings.Join(workflows.Names, ", ")))
+		}
+		if _, err := parseVersioningBehavior(behavior); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// deploymentOptions returns the worker's deployment options. c must be valid.
+func (c versioningConfig) deploymentOptions() worker.DeploymentOptions {
+	if !c.enabled() {
+		return worker.DeploymentOptions{}
+	}
+	behavior, _ := parseVersioningBehavior(c.DefaultBehavior)
+	return worker.DeploymentOptions{
+		UseVersioning: true,
+		Version: worker.WorkerDeploymentVersion{
+			DeploymentName: c.DeploymentName,
+			BuildID:        c.BuildID,
+		},
+		DefaultVersioningBehavior: behavior,
+	}
+}
+
+// behaviors returns the behaviors of individual workflows to register them
+// with. c must be valid.
+func (c versioningConfig) behaviors() map[string]workflow.VersioningBehavior {
+	behaviors := make(map[string]workflow.VersioningBehavior, len(c.WorkflowBehaviors))
+	for name, behavior := range c.WorkflowBehaviors {
+		behaviors[name], _ = parseVersioningBehavior(behavior)
+	}
+	return behaviors
+}
+
+// String describes the versioning configuration for logging.
+func (c versioningConfig) String() string {
+	if !c.enabled() {
+		return "unversioned"
+	}
+	behavior := c.DefaultBehavior
+	if behavior == "" {
+		behavior = versioningAutoUpgrade
+	}
+	s := fmt.Sprintf("deployment %s build ID %s, default behavior %s", c.DeploymentName, c.BuildID, behavior)
+
+	names := make([]string, 0, len(c.WorkflowBehaviors))
+	for name := range c.WorkflowBehaviors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		s += fmt.Sprintf(", %s %s", name, c.WorkflowBehaviors[name])
+	}
+	return s
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/workflow"
+)
+
+func TestVersioningConfig(t *testing.T) {
+	behaviors, err := parseWorkflowBehaviors("DSL:pinned,ExecuteActivity:auto-upgrade")
+	require.NoError(t, err)
+
+	c := versioningConfig{DeploymentName: "benchmark", BuildID: "v1", DefaultBehavior: versioningPinned, WorkflowBehaviors: behaviors}
+	require.NoError(t, c.validate())
+	require.True(t, c.deploymentOptions().UseVersioning)
+	require.Equal(t, workflow.VersioningBehaviorPinned, c.deploymentOptions().DefaultVersioningBehavior)
+	require.Equal(t, map[string]workflow.VersioningBehavior{
+		"DSL":             workflow.VersioningBehaviorPinned,
+		"ExecuteActivity": workflow.VersioningBehaviorAutoUpgrade,
+	}, c.behaviors())
+
+	// Without a behavior, workflows auto-upgrade.
+	c = versioningConfig{DeploymentName: "benchmark", BuildID: "v1"}
+	require.NoError(t, c.validate())
+	require.Equal(t, workflow.VersioningBehaviorAutoUpgrade, c.deploymentOptions().DefaultVersioningBehavior)
+
+	require.False(t, versioningConfig{}.deploymentOptions().UseVersioning)
+
+	for _, c := range []versioningConfig{
+		{DeploymentName: "benchmark"},
+		{BuildID: "v1"},
+		{DefaultBehavior: versioningPinned},
+		{DeploymentName: "benchmark", BuildID: "v1", DefaultBehavior: "sticky"},
+		{DeploymentName: "benchmark", BuildID: "v1", WorkflowBehaviors: map[string]string{"Unknown": versioningPinned}},
+	} {
+		require.Error(t, c.validate(), c)
+	}
+
+	_, err = parseWorkflowBehaviors("DSL")
+	require.Error(t, err)
+}
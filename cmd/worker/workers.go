@@ -0,0 +1,176 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/temporalio/benchmark-workers/activities"
+	"github.com/temporalio/benchmark-workers/workflows"
+	"go.temporal.io/sdk/worker"
+)
+
+// Worker roles. A workflows worker polls for and runs only workflow tasks, and
+// an activities worker only activity tasks, so that each can be scaled, and
+// its resource use measured, on its own.
+const (
+	roleAll        = "all"
+	roleWorkflows  = "workflows"
+	roleActivities = "activities"
+)
+
+// workerSpec describes one of the workers the process runs.
+type workerSpec struct {
+	Namespace       string
+	TaskQueue       string
+	Role            string
+	Options         worker.Options
+	WorkflowPollers pollerSpec
+	ActivityPollers pollerSpec
+	NexusPollers    pollerSpec
+	Versioning      versioningConfig
+}
+
+// workerList collects repeated -worker flags.
+type workerList []string
+
+func (l *workerList) String() string {
+	return strings.Join(*l, ";")
+}
+
+func (l *workerList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// parseWorkerSpec parses a worker spec of comma-separated key=value pairs,
+// e.g. "tq=benchmark-activities,role=activities,max-concurrent-activities=500".
+// Keys are the names of the worker's flags, along with n, tq and role.
+// Keys not given are taken from defaults. Two workers with different build-id
+// options run two versions of a deployment side by side.
+func parseWorkerSpec(spec string, defaults workerSpec) (workerSpec, error) {
+	s := defaults
+
+	// Autoscaling poller modes and workflow versioning behaviors contain
+	// commas of their own, so their later options are joined back on to them.
+	var options [][2]string
+	for _, kv := range strings.Split(spec, ",") {
+		kv = strings.TrimSpace(kv)
+		if kv == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(kv, "=")
+		if n := len(options); n > 0 && !ok && options[n-1][0] == "workflow-versioning-behavior" {
+			options[n-1][1] += "," + kv
+			continue
+		}
+		if !ok {
+			return s, fmt.Errorf("invalid worker option %q, expected key=value", kv)
+		}
+		if n := len(options); n > 0 && (key == "min" || key == "initial" || key == "max") && strings.HasSuffix(options[n-1][0], "-pollers") {
+			options[n-1][1] += "," + kv
+			continue
+		}
+		options = append(options, [2]string{key, value})
+	}
+
+	for _, kv := range options {
+		key, value := kv[0], kv[1]
+
+		var err error
+		switch key {
+		case "n", "namespace":
+			s.Namespace = value
+		case "tq", "task-queue":
+			s.TaskQueue = value
+		case "role":
+			switch value {
+			case roleAll, roleWorkflows, roleActivities:
+				s.Role = value
+			default:
+				err = fmt.Errorf("must be %s, %s or %s", roleAll, roleWorkflows, roleActivities)
+			}
+		case "workflow-pollers":
+			s.WorkflowPollers, err = parsePollerSpec(value)
+		case "activity-pollers":
+			s.ActivityPollers, err = parsePollerSpec(value)
+		case "nexus-pollers":
+			s.NexusPollers, err = parsePollerSpec(value)
+		case "max-concurrent-activities":
+			s.Options.MaxConcurrentActivityExecutionSize, err = strconv.Atoi(value)
+		case "max-concurrent-workflow-tasks":
+			s.Options.MaxConcurrentWorkflowTaskExecutionSize, err = strconv.Atoi(value)
+		case "max-concurrent-local-activities":
+			s.Options.MaxConcurrentLocalActivityExecutionSize, err = strconv.Atoi(value)
+		case "task-queue-activities-per-second":
+			s.Options.TaskQueueActivitiesPerSecond, err = strconv.ParseFloat(value, 64)
+		case "worker-activities-per-second":
+			s.Options.WorkerActivitiesPerSecond, err = strconv.ParseFloat(value, 64)
+		case "sticky-schedule-to-start-timeout":
+			s.Options.StickyScheduleToStartTimeout, err = time.ParseDuration(value)
+		case "worker-stop-timeout":
+			s.Options.WorkerStopTimeout, err = time.ParseDuration(value)
+		case "deadlock-detection-timeout":
+			s.Options.DeadlockDetectionTimeout, err = time.ParseDuration(value)
+		case "disable-eager-activities":
+			s.Options.DisableEagerActivities, err = strconv.ParseBool(value)
+		case "deployment-name":
+			s.Versioning.DeploymentName = value
+		case "build-id":
+			s.Versioning.BuildID = value
+		case "versioning-behavior":
+			s.Versioning.DefaultBehavior = value
+		case "workflow-versioning-behavior":
+			s.Versioning.WorkflowBehaviors, err = parseWorkflowBehaviors(value)
+		default:
+			return s, fmt.Errorf("unknown worker option %q", key)
+		}
+		if err != nil {
+			return s, fmt.Errorf("invalid %s %q: %w", key, value, err)
+		}
+	}
+
+	return s, nil
+}
+
+// parseWorkerSpecs parses each spec, falling back to a single default worker
+// when none are given.
+func parseWorkerSpecs(specs []string, defaults workerSpec) ([]workerSpec, error) {
+	if len(specs) == 0 {
+		return []workerSpec{defaults}, nil
+	}
+
+	var workers []workerSpec
+	for _, spec := range specs {
+		s, err := parseWorkerSpec(spec, defaults)
+		if err != nil {
+			return nil, err
+		}
+		workers = append(workers, s)
+	}
+	return workers, nil
+}
+
+// String describes the worker for logging.
+func (s workerSpec) String() string {
+	return fmt.Sprintf("namespace: %s task queue: %s role: %s", s.Namespace, s.TaskQueue, s.Role)
+}
+
+// disablePolling stops the worker polling for the types of task outside its
+// role, which it would otherwise fail.
+func (s workerSpec) disablePolling(o *worker.Options) {
+	o.DisableWorkflowWorker = s.Role == roleActivities
+	o.LocalActivityWorkerOnly = s.Role == roleWorkflows
+}
+
+// register registers the benchmark workflows and/or activities with w, as
+// its role requires.
+func (s workerSpec) register(w worker.Worker) {
+	if s.Role != roleActivities {
+		workflows.RegisterWithVersioning(w, s.Versioning.behaviors())
+	}
+	if s.Role != roleWorkflows {
+		activities.Register(w)
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/worker"
+)
+
+func TestParseWorkerSpec(t *testing.T) {
+	defaults := workerSpec{
+		Namespace:       "default",
+		TaskQueue:       "benchmark",
+		Role:            roleAll,
+		Options:         worker.Options{MaxConcurrentActivityExecutionSize: 100},
+		WorkflowPollers: pollerSpec{Mode: pollerSimple},
+		ActivityPollers: pollerSpec{Mode: pollerSimple},
+	}
+
+	s, err := parseWorkerSpec("tq=activities,role=activities,activity-pollers=autoscaling:min=2,initial=4,max=20,worker-stop-timeout=30s", defaults)
+	require.NoError(t, err)
+	require.Equal(t, "default", s.Namespace)
+	require.Equal(t, "activities", s.TaskQueue)
+	require.Equal(t, roleActivities, s.Role)
+	require.Equal(t, pollerSpec{Mode: pollerAutoscaling, Min: 2, Initial: 4, Max: 20}, s.ActivityPollers)
+	require.Equal(t, pollerSpec{Mode: pollerSimple}, s.WorkflowPollers)
+	require.Equal(t, 100, s.Options.MaxConcurrentActivityExecutionSize)
+	require.Equal(t, 30*time.Second, s.Options.WorkerStopTimeout)
+
+	s, err = parseWorkerSpec("build-id=v2,workflow-versioning-behavior=DSL:pinned,ReceiveSignal:pinned,deployment-name=benchmark", defaults)
+	require.NoError(t, err)
+	require.Equal(t, versioningConfig{
+		DeploymentName:    "benchmark",
+		BuildID:           "v2",
+		WorkflowBehaviors: map[string]string{"DSL": versioningPinned, "ReceiveSignal": versioningPinned},
+	}, s.Versioning)
+
+	for _, spec := range []string{"role=nexus", "tq", "max-concurrent-activities=lots", "min=2", "tuner=fixed"} {
+		_, err := parseWorkerSpec(spec, defaults)
+		require.Error(t, err, spec)
+	}
+
+	workers, err := parseWorkerSpecs(nil, defaults)
+	require.NoError(t, err)
+	require.Equal(t, []workerSpec{defaults}, workers)
+}
@@ -0,0 +1,40 @@
+// Package devserver starts a Temporal dev server for the binaries' integration
+// tests.
+package devserver
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"go.temporal.io/sdk/testsuite"
+)
+
+// Start starts a dev server for the duration of the test, using
+// TEMPORAL_CLI_PATH if set and the SDK's cached download otherwise. The test
+// is skipped with -short, or if the server can't be started, for example
+// because it can't be downloaded offline.
+func Start(t *testing.T) *testsuite.DevServer {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	server, err := testsuite.StartDevServer(ctx, testsuite.DevServerOptions{
+		ExistingPath: os.Getenv("TEMPORAL_CLI_PATH"),
+		LogLevel:     "error",
+	})
+	if err != nil {
+		t.Skipf("skipping integration test, dev server unavailable (set TEMPORAL_CLI_PATH to use a local temporal CLI): %v", err)
+	}
+	t.Cleanup(func() {
+		server.Client().Close()
+		server.Stop()
+	})
+
+	return server
+}
@@ -0,0 +1,194 @@
+package metrics
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"regexp"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"time"
+
+	prom "github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/uber-go/tally/v4"
+	"github.com/uber-go/tally/v4/prometheus"
+	sdktally "go.temporal.io/sdk/contrib/tally"
+	"go.temporal.io/sdk/temporal"
+)
+
+// Config configures how metrics are reported to Prometheus.
+type Config struct {
+	// ListenAddress serves the registry if set.
+	ListenAddress string
+	// TimerType is the Prometheus type timers are reported as: histogram or
+	// summary.
+	TimerType string
+	// Buckets are the default histogram bucket upper bounds, or the
+	// reporter's defaults if empty.
+	Buckets []time.Duration
+	// Objectives are the quantiles and allowed errors of summaries, or the
+	// reporter's defaults if empty.
+	Objectives []prometheus.SummaryObjective
+	// Labels are added to every metric.
+	Labels map[string]string
+	// ReportInterval is how often values are reported to the registry.
+	ReportInterval time.Duration
+}
+
+var labelNameRE = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// ConfigFromEnv reads the metrics configuration from the PROMETHEUS_*
+// environment variables.
+func ConfigFromEnv() (Config, error) {
+	c := Config{
+		ListenAddress:  os.Getenv("PROMETHEUS_ENDPOINT"),
+		TimerType:      "histogram",
+		ReportInterval: time.Second,
+	}
+
+	if v := os.Getenv("PROMETHEUS_TIMER_TYPE"); v != "" {
+		if v != "histogram" && v != "summary" {
+			return c, fmt.Errorf("invalid PROMETHEUS_TIMER_TYPE %q, expected histogram or summary", v)
+		}
+		c.TimerType = v
+	}
+
+	if v := os.Getenv("PROMETHEUS_HISTOGRAM_BUCKETS"); v != "" {
+		for _, s := range strings.Split(v, ",") {
+			d, err := time.ParseDuration(strings.TrimSpace(s))
+			if err != nil || d <= 0 {
+				return c, fmt.Errorf("invalid PROMETHEUS_HISTOGRAM_BUCKETS bucket %q, expected a positive duration", s)
+			}
+			if len(c.Buckets) > 0 && d <= c.Buckets[len(c.Buckets)-1] {
+				return c, fmt.Errorf("invalid PROMETHEUS_HISTOGRAM_BUCKETS: buckets must be increasing")
+			}
+			c.Buckets = append(c.Buckets, d)
+		}
+	}
+
+	if v := os.Getenv("PROMETHEUS_SUMMARY_OBJECTIVES"); v != "" {
+		if c.TimerType != "summary" {
+			return c, errors.New("PROMETHEUS_SUMMARY_OBJECTIVES needs PROMETHEUS_TIMER_TYPE=summary")
+		}
+		for _, pair := range strings.Split(v, ",") {
+			q, e, ok := strings.Cut(strings.TrimSpace(pair), ":")
+			quantile, qErr := strconv.ParseFloat(q, 64)
+			allowed, eErr := strconv.ParseFloat(e, 64)
+			if !ok || qErr != nil || eErr != nil || quantile <= 0 || quantile >= 1 || allowed < 0 {
+				return c, fmt.Errorf("invalid PROMETHEUS_SUMMARY_OBJECTIVES objective %q, expected quantile:error, e.g. 0.99:0.001", pair)
+			}
+			c.Objectives = append(c.Objectives, prometheus.SummaryObjective{Percentile: quantile, AllowedError: allowed})
+		}
+	}
+
+	if v := os.Getenv("PROMETHEUS_LABELS"); v != "" {
+		c.Labels = make(map[string]string)
+		for _, kv := range strings.Split(v, ",") {
+			name, value, ok := strings.Cut(strings.TrimSpace(kv), "=")
+			if !ok || !labelNameRE.MatchString(name) || strings.HasPrefix(name, "__") {
+				return c, fmt.Errorf("invalid PROMETHEUS_LABELS label %q, expected name=value", kv)
+			}
+			c.Labels[name] = value
+		}
+	}
+
+	if v := os.Getenv("PROMETHEUS_REPORT_INTERVAL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return c, fmt.Errorf("invalid PROMETHEUS_REPORT_INTERVAL: %s", v)
+		}
+		c.ReportInterval = d
+	}
+
+	return c, nil
+}
+
+// NewPrometheusScope creates a scope reporting to registry, which is served on
+// c.ListenAddress if set. Closing the returned closer reports any outstanding
+// values to the registry.
+func NewPrometheusScope(c Config, registry *prom.Registry) (tally.Scope, io.Closer) {
+	config := prometheus.Configuration{
+		ListenAddress:            c.ListenAddress,
+		TimerType:                c.TimerType,
+		DefaultSummaryObjectives: c.Objectives,
+	}
+	for _, b := range c.Buckets {
+		config.DefaultHistogramBuckets = append(config.DefaultHistogramBuckets, prometheus.HistogramObjective{Upper: b.Seconds()})
+	}
+
+	reporter, err := config.NewReporter(
+		prometheus.ConfigurationOptions{
+			Registry: registry,
+			OnError: func(err error) {
+				log.Println("error in prometheus reporter", err)
+			},
+		},
+	)
+	if err != nil {
+		log.Fatalln("error creating prometheus reporter", err)
+	}
+	scopeOpts := tally.ScopeOptions{
+		CachedReporter:  reporter,
+		Separator:       prometheus.DefaultSeparator,
+		SanitizeOptions: &sdktally.PrometheusSanitizeOptions,
+		Tags:            c.Labels,
+	}
+	scope, closer := tally.NewRootScope(scopeOpts, c.ReportInterval)
+
+	log.Println("prometheus metrics scope created")
+	return scope, closer
+}
+
+// RegisterProcessMetrics adds the Go runtime and process collectors to
+// registry, along with benchmark_build_info, so that a load generator or worker
+// that is itself the bottleneck can be told apart from a slow cluster. Each is
+// given labels.
+func RegisterProcessMetrics(registry *prom.Registry, binary string, labels map[string]string) error {
+	r := prom.WrapRegistererWith(labels, registry)
+	for _, c := range []prom.Collector{
+		collectors.NewGoCollector(collectors.WithGoCollectorRuntimeMetrics(collectors.MetricsScheduler)),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		newBuildInfo(binary),
+	} {
+		if err := r.Register(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// newBuildInfo returns the benchmark_build_info gauge, which is always 1 and
+// labelled with the binary, its module version and VCS revision, and the Go and
+// Temporal SDK versions it was built with.
+func newBuildInfo(binary string) prom.Gauge {
+	labels := prom.Labels{
+		"binary":      binary,
+		"version":     "unknown",
+		"revision":    "unknown",
+		"go_version":  runtime.Version(),
+		"sdk_version": temporal.SDKVersion,
+	}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		if info.Main.Version != "" {
+			labels["version"] = info.Main.Version
+		}
+		for _, s := range info.Settings {
+			if s.Key == "vcs.revision" {
+				labels["revision"] = s.Value
+			}
+		}
+	}
+
+	g := prom.NewGauge(prom.GaugeOpts{
+		Name:        "benchmark_build_info",
+		Help:        "Build information about the benchmark binary, always 1.",
+		ConstLabels: labels,
+	})
+	g.Set(1)
+	return g
+}
@@ -0,0 +1,95 @@
+package metrics
+
+import (
+	"runtime"
+	"testing"
+	"time"
+
+	prom "github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProcessMetrics(t *testing.T) {
+	registry := prom.NewRegistry()
+	require.NoError(t, RegisterProcessMetrics(registry, "runner", map[string]string{"experiment": "e1"}))
+
+	families, err := registry.Gather()
+	require.NoError(t, err)
+	names := make(map[string]bool)
+	for _, f := range families {
+		names[f.GetName()] = true
+		if f.GetName() == "benchmark_build_info" {
+			m := f.GetMetric()[0]
+			require.EqualValues(t, 1, m.GetGauge().GetValue())
+			labels := make(map[string]string)
+			for _, l := range m.GetLabel() {
+				labels[l.GetName()] = l.GetValue()
+			}
+			require.Equal(t, "runner", labels["binary"])
+			require.Equal(t, "e1", labels["experiment"])
+			require.Equal(t, runtime.Version(), labels["go_version"])
+			require.NotEmpty(t, labels["sdk_version"])
+		}
+	}
+	for _, name := range []string{"benchmark_build_info", "go_goroutines", "go_gc_duration_seconds", "go_memstats_heap_alloc_bytes", "go_sched_latencies_seconds", "process_cpu_seconds_total", "process_open_fds"} {
+		require.True(t, names[name], name)
+	}
+}
+
+func TestConfigFromEnv(t *testing.T) {
+	t.Setenv("PROMETHEUS_ENDPOINT", ":9090")
+	t.Setenv("PROMETHEUS_HISTOGRAM_BUCKETS", "500us,1ms,10ms")
+	t.Setenv("PROMETHEUS_LABELS", "cluster=east,experiment=e1")
+	t.Setenv("PROMETHEUS_REPORT_INTERVAL", "5s")
+	c, err := ConfigFromEnv()
+	require.NoError(t, err)
+	require.Equal(t, ":9090", c.ListenAddress)
+	require.Equal(t, "histogram", c.TimerType)
+	require.Equal(t, []time.Duration{500 * time.Microsecond, time.Millisecond, 10 * time.Millisecond}, c.Buckets)
+	require.Equal(t, map[string]string{"cluster": "east", "experiment": "e1"}, c.Labels)
+	require.Equal(t, 5*time.Second, c.ReportInterval)
+
+	t.Setenv("PROMETHEUS_TIMER_TYPE", "summary")
+	t.Setenv("PROMETHEUS_SUMMARY_OBJECTIVES", "0.5:0.05,0.99:0.001")
+	c, err = ConfigFromEnv()
+	require.NoError(t, err)
+	require.Len(t, c.Objectives, 2)
+	require.Equal(t, 0.99, c.Objectives[1].Percentile)
+
+	for env, value := range map[string]string{
+		"PROMETHEUS_TIMER_TYPE":         "gauge",
+		"PROMETHEUS_HISTOGRAM_BUCKETS":  "10ms,1ms",
+		"PROMETHEUS_SUMMARY_OBJECTIVES": "1.5:0.01",
+		"PROMETHEUS_LABELS":             "bad-name=x",
+		"PROMETHEUS_REPORT_INTERVAL":    "0s",
+	} {
+		t.Run(env, func(t *testing.T) {
+			t.Setenv(env, value)
+			_, err := ConfigFromEnv()
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestPrometheusScopeConfig(t *testing.T) {
+	registry := prom.NewRegistry()
+	scope, closer := NewPrometheusScope(Config{
+		TimerType:      "histogram",
+		Buckets:        []time.Duration{500 * time.Microsecond, time.Millisecond},
+		Labels:         map[string]string{"experiment": "e1"},
+		ReportInterval: time.Hour,
+	}, registry)
+	scope.Timer("request_latency").Record(750 * time.Microsecond)
+	require.NoError(t, closer.Close())
+
+	families, err := registry.Gather()
+	require.NoError(t, err)
+	require.Len(t, families, 1)
+	m := families[0].GetMetric()[0]
+	require.Equal(t, "experiment", m.GetLabel()[0].GetName())
+	require.Equal(t, "e1", m.GetLabel()[0].GetValue())
+	buckets := m.GetHistogram().GetBucket()
+	require.Equal(t, 0.0005, buckets[0].GetUpperBound())
+	require.EqualValues(t, 0, buckets[0].GetCumulativeCount())
+	require.EqualValues(t, 1, buckets[1].GetCumulativeCount())
+}
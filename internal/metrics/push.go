@@ -0,0 +1,261 @@
+// Package metrics sets up and publishes the Prometheus metrics of the benchmark
+// binaries.
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/golang/snappy"
+	prom "github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/push"
+	dto "github.com/prometheus/client_model/go"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Pusher periodically pushes the metrics gathered from a registry to a
+// Pushgateway and/or a Prometheus remote-write endpoint, so that short-lived
+// runs are recorded even if they are never scraped.
+type Pusher struct {
+	gatherer       prom.Gatherer
+	job            string
+	instance       string
+	pushgatewayURL string
+	remoteWriteURL string
+}
+
+// NewPusher returns a Pusher for the metrics gathered from gatherer, grouped
+// under job and the host name.
+func NewPusher(gatherer prom.Gatherer, job, pushgatewayURL, remoteWriteURL string) *Pusher {
+	instance, _ := os.Hostname()
+	return &Pusher{
+		gatherer:       gatherer,
+		job:            job,
+		instance:       instance,
+		pushgatewayURL: pushgatewayURL,
+		remoteWriteURL: remoteWriteURL,
+	}
+}
+
+// StartPushing pushes the metrics gathered from gatherer to the Pushgateway
+// and/or remote-write endpoint given by PROMETHEUS_PUSHGATEWAY_URL and
+// PROMETHEUS_REMOTE_WRITE_URL, every PROMETHEUS_PUSH_INTERVAL (default 15s),
+// as PROMETHEUS_PUSH_JOB (default defaultJob). The returned function stops
+// pushing and makes a final push, first closing flush to report outstanding
+// values to the registry. If neither URL is set, nothing is pushed and the
+// function does nothing.
+func StartPushing(gatherer prom.Gatherer, flush io.Closer, defaultJob string) (func(), error) {
+	pushgatewayURL := os.Getenv("PROMETHEUS_PUSHGATEWAY_URL")
+	remoteWriteURL := os.Getenv("PROMETHEUS_REMOTE_WRITE_URL")
+	if pushgatewayURL == "" && remoteWriteURL == "" {
+		return func() {}, nil
+	}
+
+	interval := 15 * time.Second
+	if v := os.Getenv("PROMETHEUS_PUSH_INTERVAL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid PROMETHEUS_PUSH_INTERVAL: %s", v)
+		}
+		interval = d
+	}
+	job := os.Getenv("PROMETHEUS_PUSH_JOB")
+	if job == "" {
+		job = defaultJob
+	}
+
+	p := NewPusher(gatherer, job, pushgatewayURL, remoteWriteURL)
+	ctx, cancel := context.WithCancel(context.Background())
+	go p.Run(ctx, interval)
+	log.Printf("Pushing metrics every %s as job %s", interval, job)
+
+	return func() {
+		cancel()
+
+		// Report final values to the registry before the last push.
+		flush.Close()
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := p.Push(ctx); err != nil {
+			log.Printf("Unable to push metrics: %v", err)
+		}
+	}, nil
+}
+
+// Run pushes every interval until ctx is done.
+func (p *Pusher) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := p.Push(ctx); err != nil {
+				log.Printf("Unable to push metrics: %v", err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Push pushes the current metrics once.
+func (p *Pusher) Push(ctx context.Context) error {
+	var errs []error
+	if p.pushgatewayURL != "" {
+		err := push.New(p.pushgatewayURL, p.job).
+			Gatherer(p.gatherer).
+			Grouping("instance", p.instance).
+			PushContext(ctx)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("pushgateway: %w", err))
+		}
+	}
+	if p.remoteWriteURL != "" {
+		if err := p.remoteWrite(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("remote write: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (p *Pusher) remoteWrite(ctx context.Context) error {
+	families, err := p.gatherer.Gather()
+	if err != nil {
+		return err
+	}
+
+	body := snappy.Encode(nil, encodeWriteRequest(families, map[string]string{
+		"job":      p.job,
+		"instance": p.instance,
+	}, time.Now()))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.remoteWriteURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("unexpected status %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	return nil
+}
+
+// encodeWriteRequest encodes metric families as a remote-write WriteRequest
+// protobuf, flattening histograms and summaries into their series in the same
+// way as the text exposition format.
+func encodeWriteRequest(families []*dto.MetricFamily, extraLabels map[string]string, now time.Time) []byte {
+	ts := now.UnixMilli()
+
+	var b []byte
+	for _, mf := range families {
+		name := mf.GetName()
+		for _, m := range mf.GetMetric() {
+			labels := make(map[string]string, len(m.GetLabel())+len(extraLabels)+1)
+			for k, v := range extraLabels {
+				labels[k] = v
+			}
+			for _, l := range m.GetLabel() {
+				labels[l.GetName()] = l.GetValue()
+			}
+
+			series := func(suffix string, value float64, extra ...string) {
+				l := make(map[string]string, len(labels)+2)
+				for k, v := range labels {
+					l[k] = v
+				}
+				l["__name__"] = name + suffix
+				for i := 0; i+1 < len(extra); i += 2 {
+					l[extra[i]] = extra[i+1]
+				}
+				b = appendTimeSeries(b, l, value, ts)
+			}
+
+			switch mf.GetType() {
+			case dto.MetricType_COUNTER:
+				series("", m.GetCounter().GetValue())
+			case dto.MetricType_GAUGE:
+				series("", m.GetGauge().GetValue())
+			case dto.MetricType_UNTYPED:
+				series("", m.GetUntyped().GetValue())
+			case dto.MetricType_HISTOGRAM:
+				h := m.GetHistogram()
+				for _, bucket := range h.GetBucket() {
+					series("_bucket", float64(bucket.GetCumulativeCount()), "le", formatFloat(bucket.GetUpperBound()))
+				}
+				series("_bucket", float64(h.GetSampleCount()), "le", "+Inf")
+				series("_sum", h.GetSampleSum())
+				series("_count", float64(h.GetSampleCount()))
+			case dto.MetricType_SUMMARY:
+				s := m.GetSummary()
+				for _, q := range s.GetQuantile() {
+					series("", q.GetValue(), "quantile", formatFloat(q.GetQuantile()))
+				}
+				series("_sum", s.GetSampleSum())
+				series("_count", float64(s.GetSampleCount()))
+			}
+		}
+	}
+	return b
+}
+
+// appendTimeSeries appends a TimeSeries with a single sample, as field 1 of a
+// WriteRequest.
+func appendTimeSeries(b []byte, labels map[string]string, value float64, ts int64) []byte {
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var series []byte
+	for _, name := range names {
+		var label []byte
+		label = protowire.AppendTag(label, 1, protowire.BytesType)
+		label = protowire.AppendString(label, name)
+		label = protowire.AppendTag(label, 2, protowire.BytesType)
+		label = protowire.AppendString(label, labels[name])
+
+		series = protowire.AppendTag(series, 1, protowire.BytesType)
+		series = protowire.AppendBytes(series, label)
+	}
+
+	var sample []byte
+	sample = protowire.AppendTag(sample, 1, protowire.Fixed64Type)
+	sample = protowire.AppendFixed64(sample, math.Float64bits(value))
+	sample = protowire.AppendTag(sample, 2, protowire.VarintType)
+	sample = protowire.AppendVarint(sample, uint64(ts))
+
+	series = protowire.AppendTag(series, 2, protowire.BytesType)
+	series = protowire.AppendBytes(series, sample)
+
+	b = protowire.AppendTag(b, 1, protowire.BytesType)
+	return protowire.AppendBytes(b, series)
+}
+
+func formatFloat(f float64) string {
+	if math.IsInf(f, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
@@ -0,0 +1,86 @@
+package metrics
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang/snappy"
+	prom "github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetricsPusher(t *testing.T) {
+	registry := prom.NewRegistry()
+	counter := prom.NewCounter(prom.CounterOpts{Name: "benchmark_starts_attempted"})
+	registry.MustRegister(counter)
+	counter.Add(3)
+
+	var pushPath, pushBody string
+	pushgateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		pushPath, pushBody = r.URL.Path, string(b)
+	}))
+	defer pushgateway.Close()
+
+	var writeHeader http.Header
+	var writeBody []byte
+	remoteWrite := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		writeHeader = r.Header
+		writeBody, _ = snappy.Decode(nil, b)
+	}))
+	defer remoteWrite.Close()
+
+	p := NewPusher(registry, "benchmark-runner", pushgateway.URL, remoteWrite.URL)
+	p.instance = "agent-1"
+	require.NoError(t, p.Push(context.Background()))
+
+	require.Equal(t, "/metrics/job/benchmark-runner/instance/agent-1", pushPath)
+	require.Contains(t, pushBody, "benchmark_starts_attempted")
+
+	require.Equal(t, "snappy", writeHeader.Get("Content-Encoding"))
+	require.Contains(t, string(writeBody), "benchmark_starts_attempted")
+	require.Contains(t, string(writeBody), "agent-1")
+}
+
+// closeRecorder records whether it was closed.
+type closeRecorder struct {
+	closed bool
+}
+
+func (c *closeRecorder) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestStartPushing(t *testing.T) {
+	registry := prom.NewRegistry()
+	registry.MustRegister(prom.NewCounter(prom.CounterOpts{Name: "benchmark_starts_attempted"}))
+
+	stop, err := StartPushing(registry, &closeRecorder{}, "benchmark-worker")
+	require.NoError(t, err)
+	stop()
+
+	var pushPath string
+	pushgateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		pushPath = r.URL.Path
+	}))
+	defer pushgateway.Close()
+	t.Setenv("PROMETHEUS_PUSHGATEWAY_URL", pushgateway.URL)
+
+	t.Setenv("PROMETHEUS_PUSH_INTERVAL", "soon")
+	_, err = StartPushing(registry, &closeRecorder{}, "benchmark-worker")
+	require.ErrorContains(t, err, "invalid PROMETHEUS_PUSH_INTERVAL")
+
+	// Stopping flushes outstanding values and makes a final push.
+	t.Setenv("PROMETHEUS_PUSH_INTERVAL", "1h")
+	flush := &closeRecorder{}
+	stop, err = StartPushing(registry, flush, "benchmark-worker")
+	require.NoError(t, err)
+	stop()
+	require.True(t, flush.closed)
+	require.Contains(t, pushPath, "/metrics/job/benchmark-worker/")
+}
@@ -0,0 +1,159 @@
+// Package profile profiles the benchmark binaries, on demand over HTTP or for a
+// window of their run.
+package profile
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	httppprof "net/http/pprof"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/pprof"
+	"runtime/trace"
+	"time"
+)
+
+// Sampling rates for the mutex and block profiles, which are off by default.
+// About one in mutexProfileFraction contention events, and one blocking event
+// per blockProfileRate nanoseconds spent blocked, are recorded.
+const (
+	mutexProfileFraction = 5
+	blockProfileRate     = 10000
+)
+
+// EnableContentionProfiles turns on mutex and block profiling.
+func EnableContentionProfiles() {
+	runtime.SetMutexProfileFraction(mutexProfileFraction)
+	runtime.SetBlockProfileRate(blockProfileRate)
+}
+
+// Serve serves the net/http/pprof endpoints on addr until ctx is done.
+func Serve(ctx context.Context, addr string) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", httppprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", httppprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", httppprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", httppprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", httppprof.Trace)
+
+	server := &http.Server{Addr: addr, Handler: mux}
+	go func() {
+		<-ctx.Done()
+		server.Shutdown(context.Background())
+	}()
+
+	log.Printf("Profiling endpoints listening on %s", addr)
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Fatalf("Profiling endpoints failed: %v", err)
+	}
+}
+
+// Window profiles the process for Duration, starting After the process
+// starts, and writes the profiles to Dir.
+type Window struct {
+	Dir      string
+	After    time.Duration
+	Duration time.Duration
+}
+
+// profiles are the profiles written at the end of a window, besides the CPU
+// profile and trace which cover the window itself.
+var profiles = []string{"heap", "allocs", "mutex", "block", "goroutine"}
+
+// Validate checks that the window is well formed.
+func (p Window) Validate() error {
+	if p.After < 0 {
+		return errors.New("profile start must not be negative")
+	}
+	if p.Duration <= 0 {
+		return errors.New("profile duration must be positive")
+	}
+	return nil
+}
+
+// Start runs the window in the background. The returned stop function ends
+// the window early if it is open, and waits for the profiles to be written.
+func (p Window) Start() (stop func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if err := p.Run(ctx); err != nil {
+			log.Printf("Unable to write profiles: %v", err)
+		}
+	}()
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+// Run waits for the window to open, then profiles the process until it closes
+// or ctx is done. Nothing is written if ctx is done before the window opens.
+func (p Window) Run(ctx context.Context) error {
+	select {
+	case <-time.After(p.After):
+	case <-ctx.Done():
+		return nil
+	}
+
+	if err := os.MkdirAll(p.Dir, 0o755); err != nil {
+		return err
+	}
+	EnableContentionProfiles()
+
+	cpu, err := os.Create(filepath.Join(p.Dir, "cpu.pprof"))
+	if err != nil {
+		return err
+	}
+	defer cpu.Close()
+	if err := pprof.StartCPUProfile(cpu); err != nil {
+		return fmt.Errorf("cpu profile: %w", err)
+	}
+
+	tf, err := os.Create(filepath.Join(p.Dir, "trace.out"))
+	if err != nil {
+		pprof.StopCPUProfile()
+		return err
+	}
+	defer tf.Close()
+	if err := trace.Start(tf); err != nil {
+		pprof.StopCPUProfile()
+		return fmt.Errorf("trace: %w", err)
+	}
+
+	log.Printf("Profiling for %s into %s", p.Duration, p.Dir)
+	select {
+	case <-time.After(p.Duration):
+	case <-ctx.Done():
+	}
+	trace.Stop()
+	pprof.StopCPUProfile()
+
+	// The heap profile is as of the last collection.
+	runtime.GC()
+	for _, name := range profiles {
+		if err := writeProfile(filepath.Join(p.Dir, name+".pprof"), name); err != nil {
+			return err
+		}
+	}
+
+	log.Printf("Wrote profiles to %s", p.Dir)
+	return nil
+}
+
+func writeProfile(path, name string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := pprof.Lookup(name).WriteTo(f, 0); err != nil {
+		f.Close()
+		return fmt.Errorf("%s profile: %w", name, err)
+	}
+	return f.Close()
+}
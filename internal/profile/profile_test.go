@@ -0,0 +1,49 @@
+package profile
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWindow(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "profiles")
+	p := Window{Dir: dir, After: 10 * time.Millisecond, Duration: 100 * time.Millisecond}
+	require.NoError(t, p.Validate())
+	require.NoError(t, p.Run(context.Background()))
+
+	for _, name := range []string{"cpu.pprof", "trace.out", "heap.pprof", "allocs.pprof", "mutex.pprof", "block.pprof", "goroutine.pprof"} {
+		info, err := os.Stat(filepath.Join(dir, name))
+		require.NoError(t, err, name)
+		require.NotZero(t, info.Size(), name)
+	}
+}
+
+func TestWindowStoppedEarly(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "profiles")
+
+	// Stopping before the window opens writes nothing.
+	stop := Window{Dir: dir, After: time.Hour, Duration: time.Minute}.Start()
+	stop()
+	_, err := os.Stat(dir)
+	require.True(t, os.IsNotExist(err))
+
+	// Stopping while it is open cuts it short.
+	stop = Window{Dir: dir, Duration: time.Hour}.Start()
+	require.Eventually(t, func() bool {
+		_, err := os.Stat(filepath.Join(dir, "trace.out"))
+		return err == nil
+	}, 5*time.Second, 10*time.Millisecond)
+	stop()
+	_, err = os.Stat(filepath.Join(dir, "heap.pprof"))
+	require.NoError(t, err)
+}
+
+func TestWindowValidate(t *testing.T) {
+	require.Error(t, Window{Dir: "p", After: -time.Second, Duration: time.Second}.Validate())
+	require.Error(t, Window{Dir: "p"}.Validate())
+}
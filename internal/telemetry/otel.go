@@ -0,0 +1,72 @@
+// Package telemetry exports traces and metrics from the benchmark binaries
+// over OpenTelemetry.
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// NewOpenTelemetry installs global OpenTelemetry trace and meter providers
+// that export over OTLP. The exporters are configured by the standard
+// OTEL_EXPORTER_OTLP_* environment variables, with OTEL_EXPORTER_OTLP_PROTOCOL
+// choosing between grpc (the default) and http/protobuf. The returned function
+// flushes and stops the exporters.
+func NewOpenTelemetry(ctx context.Context, serviceName string) (func(context.Context) error, error) {
+	res, err := resource.New(ctx,
+		resource.WithAttributes(attribute.String("service.name", serviceName)),
+		resource.WithFromEnv(),
+		resource.WithTelemetrySDK(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create resource: %w", err)
+	}
+
+	var traceExporter sdktrace.SpanExporter
+	var metricExporter sdkmetric.Exporter
+	switch protocol := os.Getenv("OTEL_EXPORTER_OTLP_PROTOCOL"); protocol {
+	case "", "grpc":
+		if traceExporter, err = otlptracegrpc.New(ctx); err != nil {
+			return nil, fmt.Errorf("unable to create trace exporter: %w", err)
+		}
+		if metricExporter, err = otlpmetricgrpc.New(ctx); err != nil {
+			return nil, fmt.Errorf("unable to create metric exporter: %w", err)
+		}
+	case "http/protobuf":
+		if traceExporter, err = otlptracehttp.New(ctx); err != nil {
+			return nil, fmt.Errorf("unable to create trace exporter: %w", err)
+		}
+		if metricExporter, err = otlpmetrichttp.New(ctx); err != nil {
+			return nil, fmt.Errorf("unable to create metric exporter: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported OTLP protocol: %s", protocol)
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(traceExporter),
+		sdktrace.WithResource(res),
+	)
+	mp := sdkmetric.NewMeterProvider(
+		sdkmetric.WithReader(sdkmetric.NewPeriodicReader(metricExporter)),
+		sdkmetric.WithResource(res),
+	)
+	otel.SetTracerProvider(tp)
+	otel.SetMeterProvider(mp)
+
+	return func(ctx context.Context) error {
+		return errors.Join(tp.Shutdown(ctx), mp.Shutdown(ctx))
+	}, nil
+}
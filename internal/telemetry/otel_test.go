@@ -0,0 +1,27 @@
+package telemetry
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewOpenTelemetryProtocol(t *testing.T) {
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "http://127.0.0.1:4317")
+
+	t.Setenv("OTEL_EXPORTER_OTLP_PROTOCOL", "http/json")
+	_, err := NewOpenTelemetry(context.Background(), "benchmark-test")
+	require.ErrorContains(t, err, "unsupported OTLP protocol")
+
+	for _, protocol := range []string{"", "grpc", "http/protobuf"} {
+		t.Setenv("OTEL_EXPORTER_OTLP_PROTOCOL", protocol)
+		shutdown, err := NewOpenTelemetry(context.Background(), "benchmark-test")
+		require.NoError(t, err, protocol)
+
+		// Nothing was recorded, so there is nothing to flush.
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_ = shutdown(ctx)
+	}
+}
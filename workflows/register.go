@@ -0,0 +1,22 @@
+package workflows
+
+import (
+	"go.temporal.io/sdk/worker"
+	"go.temporal.io/sdk/workflow"
+)
+
+// Names are the well-known names the benchmark workflows are registered with.
+var Names = []string{"ExecuteActivity", "ReceiveSignal", "DSL"}
+
+// Register registers the benchmark workflows with their well-known names.
+func Register(r worker.WorkflowRegistry) {
+	RegisterWithVersioning(r, nil)
+}
+
+// RegisterWithVersioning registers the benchmark workflows, giving those named
+// in behaviors that versioning behavior. The rest use the worker's default.
+func RegisterWithVersioning(r worker.WorkflowRegistry, behaviors map[string]workflow.VersioningBehavior) {
+	r.RegisterWorkflowWithOptions(ExecuteActivityWorkflow, workflow.RegisterOptions{Name: "ExecuteActivity", VersioningBehavior: behaviors["ExecuteActivity"]})
+	r.RegisterWorkflowWithOptions(ReceiveSignalWorkflow, workflow.RegisterOptions{Name: "ReceiveSignal", VersioningBehavior: behaviors["ReceiveSignal"]})
+	r.RegisterWorkflowWithOptions(DSLWorkflow, workflow.RegisterOptions{Name: "DSL", VersioningBehavior: behaviors["DSL"]})
+}